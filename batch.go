@@ -0,0 +1,378 @@
+// Copyright 2016 Appittome. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dynaGo
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/japhyf/dynaGo/internal/wait"
+)
+
+// the most requests dynamoDB accepts in one BatchWriteItem call
+const batchWriteLimit = 25
+
+// RetryOptions controls how batch executors retry the items dynamoDB
+// leaves unprocessed.  Retry n waits a random time of up to
+// BaseDelay * 2^n, capped at MaxDelay.  Zero values use the defaults.
+type RetryOptions struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+var defaultRetryOptions = RetryOptions{
+	MaxRetries: 8,
+	BaseDelay:  50 * time.Millisecond,
+	MaxDelay:   5 * time.Second,
+}
+
+// AppendPutToBatchWrite adds a put of i, as encoded by Marshal, to b.
+// BatchWriteItem has no conditions, so items that Marshal writes
+// conditionally (eg. versioned ones) are refused, as are items with
+// "unique" fields, whose sentinels need a transaction.
+func AppendPutToBatchWrite(b *dynamodb.BatchWriteItemInput, i interface{}) error {
+	in := Marshal(i)
+	if in.ConditionExpression != nil || len(getUniqueAttrNames(reflectType(i))) > 0 {
+		return &BatchConditionError{*in.TableName}
+	}
+	appendWriteRequest(b, *in.TableName, &dynamodb.WriteRequest{
+		PutRequest: &dynamodb.PutRequest{Item: in.Item},
+	})
+	return nil
+}
+
+// AppendDeleteToBatchWrite adds a delete of the item with key kv to b.
+// Items with "unique" fields are refused, their sentinels would be left
+// behind.
+func AppendDeleteToBatchWrite(b *dynamodb.BatchWriteItemInput, km KeyMaker, kv ...interface{}) error {
+	k, err := km(kv...)
+	if err != nil {
+		return err
+	}
+	if len(getUniqueAttrNames(k.typ)) > 0 {
+		return &BatchConditionError{k.tbln}
+	}
+	appendWriteRequest(b, k.tbln, &dynamodb.WriteRequest{
+		DeleteRequest: &dynamodb.DeleteRequest{Key: k.attr},
+	})
+	return nil
+}
+
+func appendWriteRequest(b *dynamodb.BatchWriteItemInput, tn string, wr *dynamodb.WriteRequest) {
+	if b.RequestItems == nil {
+		b.RequestItems = make(map[string][]*dynamodb.WriteRequest)
+	}
+	b.RequestItems[tn] = append(b.RequestItems[tn], wr)
+}
+
+// BatchWrite sends the requests of b in chunks of 25, the most a single
+// BatchWriteItem accepts, and retries unprocessed items with
+// exponential backoff.  Items that could not be written are reported
+// in a *BatchWriteError.
+func BatchWrite(svc dynamodbiface.DynamoDBAPI, b *dynamodb.BatchWriteItemInput) error {
+	return BatchWriteWithOptions(svc, b, RetryOptions{})
+}
+
+// BatchWriteWithOptions is BatchWrite retrying as described by o.
+func BatchWriteWithOptions(svc dynamodbiface.DynamoDBAPI, b *dynamodb.BatchWriteItemInput, o RetryOptions) error {
+	return BatchWriteWithContext(aws.BackgroundContext(), svc, b, o)
+}
+
+// BatchWriteWithContext is BatchWriteWithOptions with a context for the
+// requests made, and for the delay between retries, and request options
+// for the requests.
+func BatchWriteWithContext(ctx aws.Context, svc dynamodbiface.DynamoDBAPI, b *dynamodb.BatchWriteItemInput, o RetryOptions, opts ...request.Option) error {
+	o = o.withDefaults()
+	failed := make(map[string][]*dynamodb.WriteRequest)
+	chunks := chunkWriteRequests(b.RequestItems)
+	for n, chunk := range chunks {
+		pending := chunk
+		for retry := 0; len(pending) > 0; retry++ {
+			if retry > o.MaxRetries {
+				mergeWriteRequests(failed, pending)
+				break
+			}
+			if retry > 0 {
+				if err := wait.Sleep(ctx, o.delay(retry)); err != nil {
+					return failWriteRequests(failed, chunks[n:], pending, err)
+				}
+			}
+			resp, err := svc.BatchWriteItemWithContext(ctx, &dynamodb.BatchWriteItemInput{
+				RequestItems:                pending,
+				ReturnConsumedCapacity:      b.ReturnConsumedCapacity,
+				ReturnItemCollectionMetrics: b.ReturnItemCollectionMetrics,
+			}, requestOptions(ctx, opts)...)
+			if err != nil {
+				return failWriteRequests(failed, chunks[n:], pending, err)
+			}
+			pending = resp.UnprocessedItems
+		}
+	}
+	if len(failed) > 0 {
+		return &BatchWriteError{Unprocessed: failed}
+	}
+	return nil
+}
+
+// splits requests into chunks of at most batchWriteLimit, visiting
+// tables in name order
+func chunkWriteRequests(ri map[string][]*dynamodb.WriteRequest) []map[string][]*dynamodb.WriteRequest {
+	tns := make([]string, 0, len(ri))
+	for tn := range ri {
+		tns = append(tns, tn)
+	}
+	sort.Strings(tns)
+	chunks := make([]map[string][]*dynamodb.WriteRequest, 0)
+	chunk, l := make(map[string][]*dynamodb.WriteRequest), 0
+	for _, tn := range tns {
+		for _, wr := range ri[tn] {
+			if l == batchWriteLimit {
+				chunks = append(chunks, chunk)
+				chunk, l = make(map[string][]*dynamodb.WriteRequest), 0
+			}
+			chunk[tn] = append(chunk[tn], wr)
+			l++
+		}
+	}
+	if l > 0 {
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+func mergeWriteRequests(dst, src map[string][]*dynamodb.WriteRequest) {
+	for tn, wrs := range src {
+		dst[tn] = append(dst[tn], wrs...)
+	}
+}
+
+// when a call fails outright, the pending requests of the current chunk
+// and all requests of the chunks after it are reported with err
+func failWriteRequests(failed map[string][]*dynamodb.WriteRequest, rest []map[string][]*dynamodb.WriteRequest,
+	pending map[string][]*dynamodb.WriteRequest, err error) error {
+	mergeWriteRequests(failed, pending)
+	for _, chunk := range rest[1:] {
+		mergeWriteRequests(failed, chunk)
+	}
+	return &BatchWriteError{Unprocessed: failed, Err: err}
+}
+
+func (o RetryOptions) withDefaults() RetryOptions {
+	if o.MaxRetries == 0 {
+		o.MaxRetries = defaultRetryOptions.MaxRetries
+	}
+	if o.BaseDelay == 0 {
+		o.BaseDelay = defaultRetryOptions.BaseDelay
+	}
+	if o.MaxDelay == 0 {
+		o.MaxDelay = defaultRetryOptions.MaxDelay
+	}
+	return o
+}
+
+// full jitter: a random delay up to the exponential backoff of retry
+func (o RetryOptions) delay(retry int) time.Duration {
+	d := o.BaseDelay << uint(retry-1)
+	if d > o.MaxDelay || d <= 0 {
+		d = o.MaxDelay
+	}
+	return time.Duration(rand.Int63n(int64(d) + 1))
+}
+
+// the most keys dynamoDB accepts in one BatchGetItem call
+const batchGetLimit = 100
+
+// BatchGet reads all keys of b, usually built with AppendToBatchGet.
+// Duplicate keys are dropped, the keys are requested in chunks of 100,
+// the most a single BatchGetItem accepts, and unprocessed keys are
+// retried with exponential backoff.
+//
+// The items found are decoded into out, which holds pointers to slices
+// of the stored structs, eg.
+//   var usrs []Usr
+//   var tags []*Tag
+//   err := BatchGet(svc, b, &usrs, &tags)
+// Each slice receives the items of the table of its element type, in no
+// particular order.  Keys that could not be read are reported in a
+// *BatchGetError.
+func BatchGet(svc dynamodbiface.DynamoDBAPI, b *dynamodb.BatchGetItemInput, out ...interface{}) error {
+	return BatchGetWithOptions(svc, b, RetryOptions{}, out...)
+}
+
+// BatchGetWithOptions is BatchGet retrying as described by o.
+func BatchGetWithOptions(svc dynamodbiface.DynamoDBAPI, b *dynamodb.BatchGetItemInput, o RetryOptions, out ...interface{}) error {
+	return BatchGetWithContext(aws.BackgroundContext(), svc, b, o, out)
+}
+
+// BatchGetWithContext is BatchGetWithOptions with a context for the
+// requests made, and for the delay between retries, and request options
+// for the requests.
+func BatchGetWithContext(ctx aws.Context, svc dynamodbiface.DynamoDBAPI, b *dynamodb.BatchGetItemInput, o RetryOptions, out []interface{}, opts ...request.Option) error {
+	o = o.withDefaults()
+	// several entity types may share a table
+	slices := make(map[string][]reflect.Value, len(out))
+	for _, s := range out {
+		rv := reflect.ValueOf(s)
+		if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Slice {
+			return &InvalidDecodeError{reflect.TypeOf(s)}
+		}
+		et := rv.Elem().Type().Elem()
+		if et.Kind() == reflect.Ptr {
+			et = et.Elem()
+		}
+		slices[TableName(et)] = append(slices[TableName(et)], rv.Elem())
+	}
+
+	failed := make(map[string]*dynamodb.KeysAndAttributes)
+	chunks := chunkKeys(b.RequestItems)
+	for n, chunk := range chunks {
+		pending := chunk
+		for retry := 0; len(pending) > 0; retry++ {
+			if retry > o.MaxRetries {
+				mergeKeys(failed, pending)
+				break
+			}
+			if retry > 0 {
+				if err := wait.Sleep(ctx, o.delay(retry)); err != nil {
+					return failKeys(failed, chunks[n:], pending, err)
+				}
+			}
+			resp, err := svc.BatchGetItemWithContext(ctx, &dynamodb.BatchGetItemInput{
+				RequestItems:           pending,
+				ReturnConsumedCapacity: b.ReturnConsumedCapacity,
+			}, requestOptions(ctx, opts)...)
+			if err != nil {
+				return failKeys(failed, chunks[n:], pending, err)
+			}
+			for tn, items := range resp.Responses {
+				if err := appendItems(slices[tn], items); err != nil {
+					return err
+				}
+			}
+			pending = resp.UnprocessedKeys
+		}
+	}
+	if len(failed) > 0 {
+		return &BatchGetError{Unprocessed: failed}
+	}
+	return nil
+}
+
+// decodes items and appends each to the first of the slices ss whose
+// element type it is an entity of
+func appendItems(ss []reflect.Value, items []map[string]*dynamodb.AttributeValue) error {
+	for _, item := range items {
+		if isSentinel(item) {
+			continue
+		}
+		for _, s := range ss {
+			et := s.Type().Elem()
+			isPtr := et.Kind() == reflect.Ptr
+			if isPtr {
+				et = et.Elem()
+			}
+			if !isEntityOf(et, item) {
+				continue
+			}
+			o := reflect.New(et)
+			if err := Unmarshal(item, o.Interface()); err != nil {
+				return err
+			}
+			if !isPtr {
+				o = o.Elem()
+			}
+			s.Set(reflect.Append(s, o))
+			break
+		}
+	}
+	return nil
+}
+
+// splits the keys of ri into chunks of at most batchGetLimit, dropping
+// duplicate keys.  The other settings of each table are kept.
+func chunkKeys(ri map[string]*dynamodb.KeysAndAttributes) []map[string]*dynamodb.KeysAndAttributes {
+	tns := make([]string, 0, len(ri))
+	for tn := range ri {
+		tns = append(tns, tn)
+	}
+	sort.Strings(tns)
+	chunks := make([]map[string]*dynamodb.KeysAndAttributes, 0)
+	chunk, l := make(map[string]*dynamodb.KeysAndAttributes), 0
+	for _, tn := range tns {
+		ka := ri[tn]
+		seen := make(map[string]bool, len(ka.Keys))
+		for _, k := range ka.Keys {
+			ks := keyString(k)
+			if seen[ks] {
+				continue
+			}
+			seen[ks] = true
+			if l == batchGetLimit {
+				chunks = append(chunks, chunk)
+				chunk, l = make(map[string]*dynamodb.KeysAndAttributes), 0
+			}
+			if _, ok := chunk[tn]; !ok {
+				c := *ka
+				c.Keys = nil
+				chunk[tn] = &c
+			}
+			chunk[tn].Keys = append(chunk[tn].Keys, k)
+			l++
+		}
+	}
+	if l > 0 {
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+func mergeKeys(dst, src map[string]*dynamodb.KeysAndAttributes) {
+	for tn, ka := range src {
+		if _, ok := dst[tn]; !ok {
+			c := *ka
+			c.Keys = nil
+			dst[tn] = &c
+		}
+		dst[tn].Keys = append(dst[tn].Keys, ka.Keys...)
+	}
+}
+
+func failKeys(failed map[string]*dynamodb.KeysAndAttributes, rest []map[string]*dynamodb.KeysAndAttributes,
+	pending map[string]*dynamodb.KeysAndAttributes, err error) error {
+	mergeKeys(failed, pending)
+	for _, chunk := range rest[1:] {
+		mergeKeys(failed, chunk)
+	}
+	return &BatchGetError{Unprocessed: failed, Err: err}
+}
+
+// a string identifying the key k, for comparing keys
+func keyString(k map[string]*dynamodb.AttributeValue) string {
+	ns := make([]string, 0, len(k))
+	for n := range k {
+		ns = append(ns, n)
+	}
+	sort.Strings(ns)
+	s := ""
+	for _, n := range ns {
+		av := k[n]
+		switch {
+		case av.S != nil:
+			s += n + "\x00S" + *av.S + "\x00"
+		case av.N != nil:
+			s += n + "\x00N" + *av.N + "\x00"
+		default:
+			s += n + "\x00B" + string(av.B) + "\x00"
+		}
+	}
+	return s
+}
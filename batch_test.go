@@ -0,0 +1,113 @@
+// Copyright 2016 Appittome. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dynaGo
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/japhyf/dynaGo/dynagotest"
+)
+
+// fails every batch call as dynamoDB does when throttling
+type throttled struct {
+	*dynagotest.DB
+}
+
+var errThrottled = awserr.New(dynamodb.ErrCodeProvisionedThroughputExceededException, "throttled", nil)
+
+func (throttled) BatchWriteItemWithContext(aws.Context, *dynamodb.BatchWriteItemInput, ...request.Option) (*dynamodb.BatchWriteItemOutput, error) {
+	return nil, errThrottled
+}
+
+func (throttled) BatchGetItemWithContext(aws.Context, *dynamodb.BatchGetItemInput, ...request.Option) (*dynamodb.BatchGetItemOutput, error) {
+	return nil, errThrottled
+}
+
+func TestBatchWriteChunks(t *testing.T) {
+	b := &dynamodb.BatchWriteItemInput{}
+	for n := 0; n < 30; n++ {
+		if err := AppendPutToBatchWrite(b, Usr{Id: strconv.Itoa(n)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	km := CreateKeyMaker(reflect.TypeOf(Tag{}))
+	for n := 0; n < 30; n++ {
+		if err := AppendDeleteToBatchWrite(b, km, "tag", n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	chunks := chunkWriteRequests(b.RequestItems)
+	if len(chunks) != 3 {
+		t.Fatalf("expected 3 chunks, found %d", len(chunks))
+	}
+	if l := len(chunks[0]["Tags"]); l != 25 {
+		t.Errorf("expected first chunk to hold 25 Tags, found %d", l)
+	}
+	if l := len(chunks[1]["Tags"]) + len(chunks[1]["Usrs"]); l != 25 {
+		t.Errorf("expected second chunk to hold 25 items, found %d", l)
+	}
+	if l := len(chunks[2]["Usrs"]); l != 10 {
+		t.Errorf("expected last chunk to hold 10 Usrs, found %d", l)
+	}
+
+	if err := AppendPutToBatchWrite(b, Doc{Id: "a"}); err == nil {
+		t.Errorf("expected versioned items to be refused")
+	}
+}
+
+func TestBatchGetChunks(t *testing.T) {
+	b := &dynamodb.BatchGetItemInput{}
+	km := CreateKeyMaker(reflect.TypeOf(Usr{}))
+	for n := 0; n < 150; n++ {
+		if err := AppendToBatchGet(b, km, strconv.Itoa(n%120)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	chunks := chunkKeys(b.RequestItems)
+	if len(chunks) != 2 {
+		t.Fatalf("expected 2 chunks, found %d", len(chunks))
+	}
+	if l := len(chunks[0]["Usrs"].Keys) + len(chunks[1]["Usrs"].Keys); l != 120 {
+		t.Errorf("expected 120 distinct keys, found %d", l)
+	}
+}
+
+func TestBatchWriteErrorUnwraps(t *testing.T) {
+	b := &dynamodb.BatchWriteItemInput{}
+	if err := AppendPutToBatchWrite(b, Usr{Id: "1"}); err != nil {
+		t.Fatal(err)
+	}
+	err := BatchWrite(throttled{dynagotest.New()}, b)
+	var bwe *BatchWriteError
+	if !errors.As(err, &bwe) || len(bwe.Unprocessed["Usrs"]) != 1 {
+		t.Fatalf("expected the put to be unprocessed, found %v", err)
+	}
+	if !errors.Is(err, errThrottled) {
+		t.Errorf("expected the throttling error to be wrapped, found %v", bwe.Err)
+	}
+}
+
+func TestBatchGetErrorUnwraps(t *testing.T) {
+	b := &dynamodb.BatchGetItemInput{}
+	if err := AppendToBatchGet(b, CreateKeyMaker(reflect.TypeOf(Usr{})), "1"); err != nil {
+		t.Fatal(err)
+	}
+	var us []Usr
+	err := BatchGet(throttled{dynagotest.New()}, b, &us)
+	var bge *BatchGetError
+	if !errors.As(err, &bge) || len(bge.Unprocessed["Usrs"].Keys) != 1 {
+		t.Fatalf("expected the key to be unprocessed, found %v", err)
+	}
+	if !errors.Is(err, errThrottled) {
+		t.Errorf("expected the throttling error to be wrapped, found %v", bge.Err)
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2016 Appittome. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dynaGo
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// Coercion selects the conversions applied to key values given to a
+// KeyMaker made by CreateKeyMakerWithCoercion (or an IndexQuery, see
+// Coerce) that are not of the kind of their key field.  Values of the
+// same kind, eg. an int32 for an int64 key, are always accepted.  A
+// value that is not accepted is reported as a *KeyValueOfIncorrectType.
+type Coercion uint
+
+const (
+	// unsigned integers, integral floats and json.Number values for
+	// integer keys, and numbers of any kind (unix seconds) for time.Time
+	// keys
+	CoerceNumbers Coercion = 1 << iota
+	// decimal strings for integer keys, RFC 3339 strings for time.Time
+	// keys, and numbers of any kind for string keys
+	CoerceStrings
+	// time.Time values for integer keys (unix seconds, as Marshal stores
+	// time.Time fields) and for string keys (RFC 3339, UTC, as compose
+	// does)
+	CoerceTimes
+
+	CoerceNone Coercion = 0
+	CoerceAll           = CoerceNumbers | CoerceStrings | CoerceTimes
+)
+
+var jsonNumberType = reflect.TypeOf(json.Number(""))
+
+// the value of v for a string key
+func coerceString(v reflect.Value, c Coercion) (string, bool) {
+	switch {
+	case !v.IsValid():
+		return "", false
+	case v.Type() == jsonNumberType:
+		return v.String(), c&CoerceStrings != 0
+	case v.Kind() == reflect.String:
+		return v.String(), true
+	case v.Type() == timeType:
+		return v.Interface().(time.Time).UTC().Format(time.RFC3339), c&CoerceTimes != 0
+	case c&CoerceStrings == 0:
+		return "", false
+	case isInt(v):
+		return strconv.FormatInt(v.Int(), 10), true
+	case isUintKind(v.Kind()):
+		return strconv.FormatUint(v.Uint(), 10), true
+	case v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64), true
+	}
+	return "", false
+}
+
+// the value of v for an integer key
+func coerceInt(v reflect.Value, c Coercion) (int64, bool) {
+	switch {
+	case !v.IsValid():
+		return 0, false
+	case isInt(v):
+		return v.Int(), true
+	case v.Type() == jsonNumberType:
+		n, err := strconv.ParseInt(v.String(), 10, 64)
+		return n, err == nil && c&CoerceNumbers != 0
+	case v.Kind() == reflect.String:
+		n, err := strconv.ParseInt(v.String(), 10, 64)
+		return n, err == nil && c&CoerceStrings != 0
+	case v.Type() == timeType:
+		return v.Interface().(time.Time).Unix(), c&CoerceTimes != 0
+	case c&CoerceNumbers == 0:
+		return 0, false
+	case isUintKind(v.Kind()):
+		return int64(v.Uint()), v.Uint() <= math.MaxInt64
+	case v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64:
+		f := v.Float()
+		return int64(f), f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64
+	}
+	return 0, false
+}
+
+// the value of v for a time.Time key, in unix seconds
+func coerceTime(v reflect.Value, c Coercion) (int64, bool) {
+	switch {
+	case !v.IsValid():
+		return 0, false
+	case v.Type() == timeType:
+		return v.Interface().(time.Time).Unix(), true
+	case v.Type() != jsonNumberType && v.Kind() == reflect.String:
+		tm, err := time.Parse(time.RFC3339, v.String())
+		return tm.Unix(), err == nil && c&CoerceStrings != 0
+	}
+	n, ok := coerceInt(v, c)
+	return n, ok && c&CoerceNumbers != 0
+}
+
+func isUintKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+// Copyright 2016 Appittome. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dynaGo
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type Event struct {
+	Stream string    `dynaGo:",HASH"`
+	At     time.Time `dynaGo:",RANGE"`
+}
+
+func TestKeyCoercion(t *testing.T) {
+	at := time.Date(2016, 8, 1, 12, 0, 0, 0, time.UTC)
+	for _, c := range []struct {
+		coercion Coercion
+		kv       []interface{}
+		ok       bool
+	}{
+		{CoerceNone, []interface{}{"s1", int8(42)}, true},
+		{CoerceNone, []interface{}{"s1", uint(42)}, false},
+		{CoerceNumbers, []interface{}{"s1", uint(42)}, true},
+		{CoerceNumbers, []interface{}{"s1", 42.0}, true},
+		{CoerceNumbers, []interface{}{"s1", 42.5}, false},
+		{CoerceNumbers, []interface{}{"s1", json.Number("42")}, true},
+		{CoerceNumbers, []interface{}{"s1", "42"}, false},
+		{CoerceNumbers, []interface{}{nil, 42}, false},
+		{CoerceStrings, []interface{}{"s1", "42"}, true},
+		{CoerceStrings, []interface{}{"s1", "4x"}, false},
+		{CoerceAll, []interface{}{json.Number("7"), 42}, true},
+		{CoerceAll, []interface{}{"s1", at}, true},
+	} {
+		k, err := CreateKeyMakerWithCoercion(reflect.TypeOf(Message{}), c.coercion)(c.kv...)
+		if !c.ok {
+			if _, ok := err.(*KeyValueOfIncorrectType); !ok {
+				t.Errorf("expected a KeyValueOfIncorrectType for %v, found %v", c.kv, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error for %v: %v", c.kv, err)
+		} else if n := *k.attr["Timestamp"].N; n != "42" && n != "1470052800" {
+			t.Errorf("unexpected key for %v: %v", c.kv, k.attr)
+		}
+	}
+}
+
+func TestTimeKey(t *testing.T) {
+	at := time.Date(2016, 8, 1, 12, 0, 0, 0, time.UTC)
+	in := Marshal(Event{"s1", at})
+	km := CreateKeyMakerWithCoercion(reflect.TypeOf(Event{}), CoerceAll)
+	for _, kv := range []interface{}{at, at.Unix(), "2016-08-01T12:00:00Z"} {
+		k, err := km("s1", kv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(k.attr, itemKey(reflect.TypeOf(Event{}), "", in.Item)) {
+			t.Errorf("unexpected key for %v: %v", kv, k.attr)
+		}
+	}
+	k, err := KeyFrom(Event{"s1", at})
+	if err != nil || *k.attr["At"].N != "1470052800" {
+		t.Errorf("unexpected key: %v, %v", k.attr, err)
+	}
+}
+
+func TestKeyCoercionDefault(t *testing.T) {
+	km := CreateKeyMaker(reflect.TypeOf(Message{}))
+	if _, err := km("s1", uint(42)); err == nil {
+		t.Error("expected key values not to be coerced by default")
+	}
+	if _, err := NewIndexQuery(Member{}, "ByOrigin").Partition(42).Input(); err == nil {
+		t.Error("expected index key values not to be coerced by default")
+	}
+	in, err := NewIndexQuery(Member{}, "ByOrigin").Coerce(CoerceStrings).Partition(42).Input()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, av := range in.ExpressionAttributeValues {
+		if av.S == nil || *av.S != "42" {
+			t.Errorf("unexpected index key value: %v", av)
+		}
+	}
+}
@@ -0,0 +1,318 @@
+// Copyright 2016 Appittome. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dynaGo
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// A string field tagged "compose" holds a synthetic attribute made of
+// other fields, joined by "#", as single table designs do for sort keys:
+//   Kind string
+//   At   time.Time
+//   Id   string
+//   SK   string `dynaGo:"SK,RANGE,compose=Kind#At#Id"`
+// Components are named by field or attribute name, any other component
+// is a literal ("compose=MSG#At#Id").  Marshal writes the attribute,
+// Unmarshal splits it back into the fields, and a KeyMaker takes the
+// component values in place of the composed one, coerced as the plain
+// key values are.
+//
+// Components are strings, integers or time.Time, written so that they
+// sort as strings do: integers in decimal, zero padded to the width of
+// their type (an int64 of 7 is "0000000000000000007"), and times as
+// RFC 3339 in UTC.  Negative integers do not sort.  Only the last
+// component may itself contain "#", Marshal panics and a KeyMaker
+// returns a *ComposedValueError for any other.  When every field
+// component is zero, the field is written as it is.
+//
+// Overloaded index keys are usually sparse: an entity only appears in
+// the index once all the fields its key is made of are set.  A composed
+// attribute tagged "sparse" is only written when none of its field
+// components is zero, and left out of the item otherwise:
+//   GSI1PK string `dynaGo:",gsi=GSI1,compose=ORG#OrgId,sparse"`
+// An Update removes the stored attribute in that case, so the item
+// leaves the index.
+const (
+	tagCompose = "compose"
+	tagSparse  = "sparse"
+	composeSep = "#"
+)
+
+// one part of a composed attribute, either a field or a literal
+type composePart struct {
+	index   []int
+	typ     reflect.Type
+	literal string
+}
+
+type composition struct {
+	name   string // of the composed attribute
+	prefix string
+	sparse bool
+	parts  []composePart
+}
+
+// the field components of c, those a KeyMaker is given values for
+func (c *composition) fields() int {
+	n := 0
+	for _, p := range c.parts {
+		if p.index != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// the composition declared by sf of t, nil if sf has none.  Panics if sf
+// is not a string, or a component is of an unsupported kind.
+func getComposition(t reflect.Type, sf reflect.StructField) *composition {
+	_, opts := parseTag(sf.Tag.Get("dynaGo"))
+	vs := opts.Values(tagCompose)
+	if len(vs) == 0 {
+		return nil
+	}
+	if sf.Type.Kind() != reflect.String {
+		panic(&TagOptionKindError{tagCompose, sf.Type.Kind()})
+	}
+	c := &composition{name: getAttrName(sf), prefix: getPrefix(sf), sparse: opts.Contains(tagSparse)}
+	for _, s := range strings.Split(vs[0], composeSep) {
+		c.parts = append(c.parts, composePart{literal: s})
+		for n := 0; n < t.NumField(); n++ {
+			f := t.Field(n)
+			if f.Name == sf.Name || f.Name != s && getAttrName(f) != s {
+				continue
+			}
+			if f.Type != timeType && f.Type.Kind() != reflect.String && !isIntKind(f.Type.Kind()) {
+				panic(&TagOptionKindError{tagCompose, f.Type.Kind()})
+			}
+			c.parts[len(c.parts)-1] = composePart{index: f.Index, typ: f.Type}
+			break
+		}
+	}
+	return c
+}
+
+// the compositions of t, in field order
+func getCompositions(t reflect.Type) []*composition {
+	var cs []*composition
+	for n := 0; n < t.NumField(); n++ {
+		if c := getComposition(t, t.Field(n)); c != nil {
+			cs = append(cs, c)
+		}
+	}
+	return cs
+}
+
+// the composed value of v, and whether any and whether all of its field
+// components are set
+func (c *composition) compose(v reflect.Value) (s string, any, all bool) {
+	ss := make([]string, len(c.parts))
+	all = true
+	for i, p := range c.parts {
+		if p.index == nil {
+			ss[i] = p.literal
+			continue
+		}
+		fv := v.FieldByIndex(p.index)
+		set := !isZeroValue(fv)
+		any, all = any || set, all && set
+		ss[i] = composeValue(fv, p.typ)
+		if i < len(c.parts)-1 && strings.Contains(ss[i], composeSep) {
+			panic(&ComposedValueError{c.name, ss[i]})
+		}
+	}
+	return strings.Join(ss, composeSep), any, all
+}
+
+// the composed value of the component values kv, as given to a KeyMaker
+// converting them as co allows
+func (c *composition) composeValues(kv []interface{}, co Coercion) (string, error) {
+	ss := make([]string, len(c.parts))
+	for i, p := range c.parts {
+		if p.index == nil {
+			ss[i] = p.literal
+			continue
+		}
+		k := reflect.ValueOf(kv[0])
+		kv = kv[1:]
+		var ok bool
+		switch {
+		case p.typ == timeType:
+			var n int64
+			n, ok = coerceTime(k, co)
+			ss[i] = composeValue(reflect.ValueOf(time.Unix(n, 0)), p.typ)
+		case p.typ.Kind() == reflect.String:
+			ss[i], ok = coerceString(k, co)
+		default:
+			var n int64
+			n, ok = coerceInt(k, co)
+			ss[i] = composeValue(reflect.ValueOf(n), p.typ)
+		}
+		if !ok {
+			return "", &KeyValueOfIncorrectType{p.typ.Kind(), k.Kind()}
+		}
+		if i < len(c.parts)-1 && strings.Contains(ss[i], composeSep) {
+			return "", &ComposedValueError{c.name, ss[i]}
+		}
+	}
+	return strings.Join(ss, composeSep), nil
+}
+
+// the start of the composed value made of the leading component values
+// kv, up to the separator following the last of them, as used to query
+// for items that begin with it.  Without values, the leading literals.
+func (c *composition) composePrefix(kv []interface{}, co Coercion) (string, error) {
+	n := 0
+	for i, p := range c.parts {
+		if p.index == nil {
+			continue
+		}
+		if n == len(kv) {
+			// the empty literal ends the prefix with a separator
+			pc := &composition{name: c.name, parts: append(c.parts[:i:i], composePart{})}
+			return pc.composeValues(kv, co)
+		}
+		n++
+	}
+	if n < len(kv) {
+		return "", fmt.Errorf("dynaGo: %s takes at most %d values, found %d", c.name, n, len(kv))
+	}
+	return c.composeValues(kv, co)
+}
+
+// sets the field components of v from the composed value s
+func (c *composition) decompose(s string, v reflect.Value) error {
+	if !strings.HasPrefix(s, c.prefix) {
+		return &ComposedValueError{c.name, s}
+	}
+	s = strings.TrimPrefix(s, c.prefix)
+	ss := strings.SplitN(s, composeSep, len(c.parts))
+	if len(ss) != len(c.parts) {
+		return &ComposedValueError{c.name, s}
+	}
+	for i, p := range c.parts {
+		if p.index == nil {
+			if ss[i] != p.literal {
+				return &ComposedValueError{c.name, s}
+			}
+			continue
+		}
+		fv := v.FieldByIndex(p.index)
+		switch {
+		case p.typ == timeType:
+			tm, err := time.Parse(time.RFC3339, ss[i])
+			if err != nil {
+				return &ComposedValueError{c.name, s}
+			}
+			fv.Set(reflect.ValueOf(tm))
+		case p.typ.Kind() == reflect.String:
+			fv.SetString(ss[i])
+		default:
+			n, err := strconv.ParseInt(ss[i], 10, 64)
+			if err != nil {
+				return &ComposedValueError{c.name, s}
+			}
+			fv.SetInt(n)
+		}
+	}
+	return nil
+}
+
+// v as a component of type t
+func composeValue(v reflect.Value, t reflect.Type) string {
+	switch {
+	case t == timeType:
+		return v.Interface().(time.Time).UTC().Format(time.RFC3339)
+	case t.Kind() == reflect.String:
+		return v.String()
+	}
+	return fmt.Sprintf("%0*d", intWidth(t.Kind()), v.Int())
+}
+
+// the digits of the largest value of the int kind k
+func intWidth(k reflect.Kind) int {
+	switch k {
+	case reflect.Int8:
+		return 3
+	case reflect.Int16:
+		return 5
+	case reflect.Int32:
+		return 10
+	}
+	return 19
+}
+
+func isZeroValue(v reflect.Value) bool {
+	if v.Type() == timeType {
+		return v.Interface().(time.Time).IsZero()
+	}
+	return v.Interface() == reflect.Zero(v.Type()).Interface()
+}
+
+// writes the composed attributes of v to item
+func applyCompositions(v reflect.Value, item map[string]*dynamodb.AttributeValue) {
+	for _, c := range getCompositions(v.Type()) {
+		s, any, all := c.compose(v)
+		switch {
+		case c.sparse && !all:
+			delete(item, c.name)
+		case any:
+			s = c.prefix + s
+			item[c.name] = &dynamodb.AttributeValue{S: &s}
+		}
+	}
+}
+
+// the sparse composed attributes of v that are left out for a zero
+// component, and so are removed by an update
+func sparseRemovals(v reflect.Value) []string {
+	var ns []string
+	for _, c := range getCompositions(v.Type()) {
+		if _, _, all := c.compose(v); c.sparse && !all {
+			ns = append(ns, c.name)
+		}
+	}
+	return ns
+}
+
+// sets the components of v from the composed attributes of item
+func readCompositions(item map[string]*dynamodb.AttributeValue, v reflect.Value) error {
+	for _, c := range getCompositions(v.Type()) {
+		av, ok := item[c.name]
+		if !ok || av.S == nil {
+			continue
+		}
+		if err := c.decompose(*av.S, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// the key value for the key field sf of t taken from the front of kv,
+// and the values left.  A composed key takes either a value for each of
+// its field components or, as the last key, the composed string itself.
+func keyValue(t reflect.Type, sf reflect.StructField, kv []interface{}, last bool, co Coercion) (interface{}, []interface{}, error) {
+	c := getComposition(t, sf)
+	if c == nil {
+		return kv[0], kv[1:], nil
+	}
+	n := c.fields()
+	if last && len(kv) == 1 && n != 1 {
+		return kv[0], nil, nil
+	}
+	if len(kv) < n || last && len(kv) != n {
+		return nil, nil, fmt.Errorf("dynaGo:%s KeyMaker: %s takes %d values, found %d", t.Name(), c.name, n, len(kv))
+	}
+	s, err := c.composeValues(kv[:n], co)
+	return s, kv[n:], err
+}
@@ -0,0 +1,142 @@
+// Copyright 2016 Appittome. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dynaGo
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type Post struct {
+	Thread string `dynaGo:",HASH"`
+	At     time.Time
+	Id     int64
+	SK     string `dynaGo:",RANGE,compose=MSG#At#Id"`
+	Body   string
+}
+
+func TestCompose(t *testing.T) {
+	at := time.Date(2016, 8, 1, 12, 0, 0, 0, time.UTC)
+	in := Marshal(Post{Thread: "t1", At: at, Id: 7, Body: "hi"})
+	if sk := in.Item["SK"]; sk == nil || *sk.S != "MSG#2016-08-01T12:00:00Z#0000000000000000007" {
+		t.Fatalf("unexpected composed attribute: %v", in.Item["SK"])
+	}
+
+	var p Post
+	if err := Unmarshal(in.Item, &p); err != nil {
+		t.Fatal(err)
+	}
+	if !p.At.Equal(at) || p.Id != 7 || p.SK != "MSG#2016-08-01T12:00:00Z#0000000000000000007" {
+		t.Errorf("unexpected decoded post: %v", p)
+	}
+	in.Item["SK"].S = in.Item["Thread"].S
+	if err := Unmarshal(in.Item, &p); err == nil {
+		t.Error("expected an error for a malformed composed attribute")
+	}
+
+	km := CreateKeyMaker(reflect.TypeOf(Post{}))
+	for _, kv := range [][]interface{}{
+		{"t1", at, 7},
+		{"t1", "MSG#2016-08-01T12:00:00Z#0000000000000000007"},
+	} {
+		k, err := km(kv...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *k.attr["SK"].S != "MSG#2016-08-01T12:00:00Z#0000000000000000007" {
+			t.Errorf("unexpected key from %v: %v", kv, k.attr)
+		}
+	}
+	if _, err := km("t1", at); err == nil {
+		t.Error("expected an error for missing components")
+	}
+	if _, err := km("t1", "x", 7); err == nil {
+		t.Error("expected an error for a component of the wrong type")
+	}
+}
+
+type Line struct {
+	Doc  string `dynaGo:",HASH"`
+	Page int16
+	No   int
+	SK   string `dynaGo:",RANGE,compose=Page#No"`
+}
+
+func TestComposeSortsIntegers(t *testing.T) {
+	var sks []string
+	for _, l := range []Line{{Doc: "d", Page: 1, No: 10}, {Doc: "d", Page: 1, No: 9}, {Doc: "d", Page: 10, No: 1}, {Doc: "d", Page: 9, No: 1}} {
+		sks = append(sks, *Marshal(l).Item["SK"].S)
+	}
+	if sks[0] != "00001#0000000000000000010" {
+		t.Errorf("unexpected composed attribute: %s", sks[0])
+	}
+	if !(sks[1] < sks[0] && sks[0] < sks[3] && sks[3] < sks[2]) {
+		t.Errorf("expected composed integers to sort by value: %v", sks)
+	}
+
+	var l Line
+	if err := Unmarshal(Marshal(Line{Doc: "d", Page: 3, No: 42}).Item, &l); err != nil {
+		t.Fatal(err)
+	}
+	if l.Page != 3 || l.No != 42 {
+		t.Errorf("unexpected decoded line: %v", l)
+	}
+}
+
+type Reply struct {
+	Thread string `dynaGo:",HASH"`
+	Author string
+	Id     int
+	SK     string `dynaGo:",RANGE,compose=Author#Id"`
+}
+
+func TestComposeSeparatorInComponent(t *testing.T) {
+	// the last component may contain the separator
+	in := Marshal(Employee{PK: "p", SK: "s", OrgId: "o", Name: "a#b"})
+	if sk := in.Item["GSI1SK"]; sk == nil || *sk.S != "EMP#a#b" {
+		t.Errorf("unexpected composed attribute: %v", in.Item["GSI1SK"])
+	}
+
+	func() {
+		defer func() {
+			if _, ok := recover().(*ComposedValueError); !ok {
+				t.Error("expected Marshal to panic with a *ComposedValueError")
+			}
+		}()
+		Marshal(Reply{Thread: "t1", Author: "a#b", Id: 1})
+	}()
+	km := CreateKeyMaker(reflect.TypeOf(Reply{}))
+	if _, err := km("t1", "a#b", 1); err == nil {
+		t.Error("expected an error for a separator in a component")
+	} else if _, ok := err.(*ComposedValueError); !ok {
+		t.Errorf("expected a *ComposedValueError, found %T", err)
+	}
+}
+
+func TestComposeCoercion(t *testing.T) {
+	at := time.Date(2016, 8, 1, 12, 0, 0, 0, time.UTC)
+	if _, err := CreateKeyMaker(reflect.TypeOf(Post{}))("t1", at, "7"); err == nil {
+		t.Error("expected components not to be coerced by default")
+	}
+	km := CreateKeyMakerWithCoercion(reflect.TypeOf(Post{}), CoerceAll)
+	for _, kv := range [][]interface{}{
+		{"t1", at, "7"},
+		{"t1", at, uint(7)},
+		{"t1", at.Unix(), 7.0},
+		{"t1", "2016-08-01T12:00:00Z", json.Number("7")},
+	} {
+		k, err := km(kv...)
+		if err != nil {
+			t.Errorf("unexpected error for %v: %v", kv, err)
+		} else if *k.attr["SK"].S != "MSG#2016-08-01T12:00:00Z#0000000000000000007" {
+			t.Errorf("unexpected key from %v: %v", kv, k.attr)
+		}
+	}
+	if _, err := km("t1", at, "x"); err == nil {
+		t.Error("expected an error for a component that cannot be coerced")
+	}
+}
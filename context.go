@@ -0,0 +1,52 @@
+// Copyright 2016 Appittome. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dynaGo
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+// Every operation dynaGo performs has a variant taking a context, which
+// is handed to the *WithContext methods of the sdk, along with the sdk
+// request options given after the other arguments, as the sdk does:
+//
+//	err := dynaGo.PutWithContext(ctx, svc, usr, request.WithLogLevel(aws.LogDebug))
+//
+// Where the operation already takes a variadic list of keys or values,
+// its *WithContext variant takes them as a slice.
+
+type requestOptionsKey struct{}
+
+// WithRequestOptions returns a copy of ctx whose dynaGo operations apply
+// opts to every request they make, after any options already in ctx and
+// before those given to the operation.
+//
+// Deprecated: give the options to the *WithContext variant of the
+// operation instead.
+func WithRequestOptions(ctx aws.Context, opts ...request.Option) aws.Context {
+	prev := contextOptions(ctx)
+	all := make([]request.Option, 0, len(prev)+len(opts))
+	all = append(append(all, prev...), opts...)
+	return context.WithValue(ctx, requestOptionsKey{}, all)
+}
+
+func contextOptions(ctx aws.Context) []request.Option {
+	opts, _ := ctx.Value(requestOptionsKey{}).([]request.Option)
+	return opts
+}
+
+// the options of a request made with ctx on behalf of an operation given
+// opts
+func requestOptions(ctx aws.Context, opts []request.Option) []request.Option {
+	prev := contextOptions(ctx)
+	if len(prev) == 0 {
+		return opts
+	}
+	all := make([]request.Option, 0, len(prev)+len(opts))
+	return append(append(all, prev...), opts...)
+}
@@ -0,0 +1,87 @@
+// Copyright 2016 Appittome. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dynaGo
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/japhyf/dynaGo/dynagotest"
+)
+
+// records the request options of every PutItem and GetItem
+type optionRecorder struct {
+	*dynagotest.DB
+	opts [][]request.Option
+}
+
+func (r *optionRecorder) PutItemWithContext(ctx aws.Context, in *dynamodb.PutItemInput, opts ...request.Option) (*dynamodb.PutItemOutput, error) {
+	r.opts = append(r.opts, opts)
+	return r.DB.PutItemWithContext(ctx, in, opts...)
+}
+
+func (r *optionRecorder) GetItemWithContext(ctx aws.Context, in *dynamodb.GetItemInput, opts ...request.Option) (*dynamodb.GetItemOutput, error) {
+	r.opts = append(r.opts, opts)
+	return r.DB.GetItemWithContext(ctx, in, opts...)
+}
+
+func TestRequestOptions(t *testing.T) {
+	r := &optionRecorder{DB: dynagotest.New()}
+	if err := CreateTable(r, Doc{}, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	var order []string
+	opt := func(name string) request.Option {
+		return func(*request.Request) { order = append(order, name) }
+	}
+	ctx := aws.BackgroundContext()
+	if err := PutWithContext(ctx, r, Doc{Id: "a"}, opt("put")); err != nil {
+		t.Fatal(err)
+	}
+	// options in the context come before those given to the operation
+	ctx = WithRequestOptions(ctx, opt("ctx"))
+	if err := GetWithContext(ctx, r, &Doc{Id: "a"}, opt("get")); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.opts) != 2 {
+		t.Fatalf("expected 2 requests, found %d", len(r.opts))
+	}
+	for _, opts := range r.opts {
+		(&request.Request{}).ApplyOptions(opts...)
+	}
+	if want := []string{"put", "ctx", "get"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("expected options %v, found %v", want, order)
+	}
+}
+
+func TestWithRequestOptions(t *testing.T) {
+	nop := func(*request.Request) {}
+	ctx := WithRequestOptions(aws.BackgroundContext(), nop)
+	child := WithRequestOptions(ctx, nop, nop)
+	if len(contextOptions(ctx)) != 1 || len(contextOptions(child)) != 3 {
+		t.Errorf("unexpected options: %d, %d", len(contextOptions(ctx)), len(contextOptions(child)))
+	}
+	if n := len(requestOptions(child, []request.Option{nop})); n != 4 {
+		t.Errorf("expected 4 options, found %d", n)
+	}
+}
+
+func TestContextCanceled(t *testing.T) {
+	db := dynagotest.New()
+	ctx, cancel := context.WithCancel(aws.BackgroundContext())
+	if err := CreateTableWithContext(ctx, db, Doc{}, TableOptions{BillingMode: dynamodb.BillingModePayPerRequest}); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	err := PutWithContext(ctx, db, Doc{Id: "a"})
+	if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != request.CanceledErrorCode {
+		t.Errorf("expected a canceled request, found %v", err)
+	}
+}
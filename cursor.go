@@ -0,0 +1,143 @@
+// Copyright 2016 Appittome. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dynaGo
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// CursorCodec converts the LastEvaluatedKey of a Query or Scan into an
+// opaque, URL safe cursor that can be handed to clients, and converts
+// such cursors back into an ExclusiveStartKey.
+//
+// When Secret is set, cursors are signed with HMAC-SHA256 and Decode
+// refuses any cursor without a valid signature, so clients cannot forge
+// keys.  Unsigned cursors are merely encoded.
+type CursorCodec struct {
+	Secret []byte
+}
+
+// one key attribute, T is "S", "N" or "B" (base64 encoded)
+type cursorAttr struct {
+	T string `json:"t"`
+	V string `json:"v"`
+}
+
+// Encode returns the cursor for key k, or "" if k is empty, meaning
+// there are no further results.
+func (c CursorCodec) Encode(k map[string]*dynamodb.AttributeValue) (string, error) {
+	if len(k) == 0 {
+		return "", nil
+	}
+	m := make(map[string]cursorAttr, len(k))
+	for n, av := range k {
+		switch {
+		case av.S != nil:
+			m[n] = cursorAttr{dynamodb.ScalarAttributeTypeS, *av.S}
+		case av.N != nil:
+			m[n] = cursorAttr{dynamodb.ScalarAttributeTypeN, *av.N}
+		case av.B != nil:
+			m[n] = cursorAttr{dynamodb.ScalarAttributeTypeB, base64.StdEncoding.EncodeToString(av.B)}
+		default:
+			return "", &InvalidCursorError{"key attribute " + n + " is not a scalar"}
+		}
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+	s := base64.RawURLEncoding.EncodeToString(b)
+	if c.Secret != nil {
+		s += "." + base64.RawURLEncoding.EncodeToString(c.sign(b))
+	}
+	return s, nil
+}
+
+// Decode returns the ExclusiveStartKey encoded in cursor, after checking
+// that it is a primary key of the table of rt, as CreateKeyMaker would
+// build it.  An empty cursor decodes to a nil key.
+func (c CursorCodec) Decode(cursor string, rt reflect.Type) (map[string]*dynamodb.AttributeValue, error) {
+	return c.DecodeIndex(cursor, rt, "")
+}
+
+// DecodeIndex is Decode for cursors of a Query or Scan on the global
+// secondary index named index, whose keys also hold the index key.
+func (c CursorCodec) DecodeIndex(cursor string, rt reflect.Type, index string) (map[string]*dynamodb.AttributeValue, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+	parts := strings.Split(cursor, ".")
+	b, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, &InvalidCursorError{"malformed cursor"}
+	}
+	if c.Secret != nil {
+		if len(parts) != 2 {
+			return nil, &InvalidCursorError{"cursor is not signed"}
+		}
+		sig, err := base64.RawURLEncoding.DecodeString(parts[1])
+		if err != nil || !hmac.Equal(sig, c.sign(b)) {
+			return nil, &InvalidCursorError{"invalid signature"}
+		}
+	} else if len(parts) != 1 {
+		return nil, &InvalidCursorError{"malformed cursor"}
+	}
+
+	m := make(map[string]cursorAttr)
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, &InvalidCursorError{"malformed cursor"}
+	}
+	return cursorKey(rt, index, m)
+}
+
+func (c CursorCodec) sign(b []byte) []byte {
+	h := hmac.New(sha256.New, c.Secret)
+	h.Write(b)
+	return h.Sum(nil)
+}
+
+// rebuilds the key from m, which must hold exactly the key attributes
+// of the table (and index) with the types of their definitions.
+func cursorKey(rt reflect.Type, index string, m map[string]cursorAttr) (map[string]*dynamodb.AttributeValue, error) {
+	if rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	ads := attributeDefinitionMap(typeSchema(rt).AttributeDefinitions)
+	ns := append(keyAttrNames(rt), indexKeyAttrNames(rt, index)...)
+	k := make(map[string]*dynamodb.AttributeValue, len(ns))
+	for _, n := range ns {
+		ca, ok := m[n]
+		if !ok {
+			return nil, &InvalidCursorError{"missing key attribute " + n}
+		}
+		if ca.T != ads[n] {
+			return nil, &InvalidCursorError{"key attribute " + n + " has type " + ca.T + ", expected " + ads[n]}
+		}
+		v := ca.V
+		switch ca.T {
+		case dynamodb.ScalarAttributeTypeS:
+			k[n] = &dynamodb.AttributeValue{S: &v}
+		case dynamodb.ScalarAttributeTypeN:
+			k[n] = &dynamodb.AttributeValue{N: &v}
+		case dynamodb.ScalarAttributeTypeB:
+			b, err := base64.StdEncoding.DecodeString(v)
+			if err != nil {
+				return nil, &InvalidCursorError{"malformed cursor"}
+			}
+			k[n] = &dynamodb.AttributeValue{B: b}
+		}
+	}
+	if len(m) != len(k) {
+		return nil, &InvalidCursorError{"unexpected key attributes"}
+	}
+	return k, nil
+}
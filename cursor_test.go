@@ -0,0 +1,43 @@
+// Copyright 2016 Appittome. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dynaGo
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func TestCursor(t *testing.T) {
+	lek := map[string]*dynamodb.AttributeValue{
+		"SessionId": {S: aws.String("abc")},
+		"Timestamp": {N: aws.String("1234")},
+	}
+	c := CursorCodec{Secret: []byte("shh")}
+	s, err := c.Encode(lek)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := c.Decode(s, reflect.TypeOf(Message{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(k, lek) {
+		t.Errorf("expected %v, found %v", lek, k)
+	}
+
+	if _, err := (CursorCodec{Secret: []byte("guess")}).Decode(s, reflect.TypeOf(Message{})); err == nil {
+		t.Errorf("expected a cursor signed with another secret to be refused")
+	}
+	if _, err := c.Decode(s, reflect.TypeOf(Usr{})); err == nil {
+		t.Errorf("expected a Message cursor to be refused for Usrs")
+	}
+	forged, _ := CursorCodec{}.Encode(lek)
+	if _, err := c.Decode(forged, reflect.TypeOf(Message{})); err == nil {
+		t.Errorf("expected an unsigned cursor to be refused")
+	}
+}
@@ -7,6 +7,7 @@ package dynaGo
 import (
 	"reflect"
 	"strconv"
+	"time"
 
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 )
@@ -55,13 +56,19 @@ func Unmarshal(m map[string]*dynamodb.AttributeValue, i interface{}) error {
 	for i, field := range typeFields(et) {
 		if av, ok := m[field.name]; ok {
 			f := ev.Field(i)
+			if p := getPrefix(et.Field(i)); p != "" {
+				av = stripPrefix(p, et.Field(i), av)
+			}
 			decoder(f.Type())(av, f)
 		}
 	}
-	return nil
+	return readCompositions(m, ev)
 }
 
 func decoder(t reflect.Type) decoderFunc {
+	if t == timeType {
+		return timeDecoder
+	}
 	switch t.Kind() {
 	case reflect.String:
 		return stringDecoder
@@ -94,6 +101,10 @@ func intDecoder(av *dynamodb.AttributeValue, rv reflect.Value) {
 	n, _ := strconv.ParseInt(*av.N, 10, 64)
 	rv.SetInt(n)
 }
+func timeDecoder(av *dynamodb.AttributeValue, rv reflect.Value) {
+	n, _ := strconv.ParseInt(*av.N, 10, 64)
+	rv.Set(reflect.ValueOf(time.Unix(n, 0)))
+}
 func byteSliceDecoder(av *dynamodb.AttributeValue, rv reflect.Value) {
 	rv.Set(reflect.ValueOf(av.B))
 }
@@ -5,26 +5,20 @@
 package dynaGo
 
 import (
+	"os"
 	"reflect"
 	"testing"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/japhyf/dynaGo/dynagotest"
 )
 
 // Don't think this test will ever fail unless someone panics.
 func TestDecode(t *testing.T) {
-	cred := &credentials.SharedCredentialsProvider{Profile: "admin_marcus"}
-	svc := dynamodb.New(
-		session.New(),
-		&aws.Config{
-			Credentials: credentials.NewCredentials(cred),
-			Endpoint:    aws.String("http://localhost:8000"),
-			Region:      aws.String("us-east-1"),
-		})
+	svc := decodeService(t)
 
 	//pointer to session
 	msgs := exercise(t, svc, Message{}).([]*Message)
@@ -69,25 +63,41 @@ func TestDecode(t *testing.T) {
 
 }
 
-// dynamodb.Scans table.  First page is returned as an array of pointers of the
+// TestDecode runs before the encode tests fill the tables, so against the
+// fake it reads a copy of their fixtures instead.
+func decodeService(t *testing.T) dynamodbiface.DynamoDBAPI {
+	if os.Getenv("DYNAGO_TEST_ENDPOINT") != "" {
+		return svc
+	}
+	db := dynagotest.New()
+	for _, v := range []interface{}{Tag{}, Usr{}, Session{}, Message{}} {
+		if err := CreateTable(db, v, 1, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, v := range []interface{}{msg, ses0, ses1, tag, usr0, usr1} {
+		if _, err := db.PutItem(Marshal(v)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+// dynamodb.Scans table.  Every page is returned as an array of pointers of the
 // type of the interface passed in.  eg exercise(t,svc, Usr{}) returns []*Usr
-func exercise(t *testing.T, svc *dynamodb.DynamoDB, i interface{}) interface{} {
+func exercise(t *testing.T, svc dynamodbiface.DynamoDBAPI, i interface{}) interface{} {
 	param := &dynamodb.ScanInput{
 		TableName: aws.String(TableName(reflect.TypeOf(i))),
 	}
 
-	resp, err := svc.Scan(param)
-	if err != nil {
-		t.Error(err)
+	rt := reflect.TypeOf(i)
+	items := reflect.MakeSlice(reflect.SliceOf(reflect.PtrTo(rt)), 0, 0)
+	it := NewScanIterator(aws.BackgroundContext(), svc, param, i)
+	for it.Next() {
+		items = reflect.Append(items, reflect.ValueOf(it.Item()))
 	}
-	l, rt := len(resp.Items), reflect.TypeOf(i)
-	items := reflect.MakeSlice(reflect.SliceOf(reflect.PtrTo(rt)), l, l)
-	for n, item := range resp.Items {
-		o := reflect.New(rt)
-		if err = Unmarshal(item, o.Interface()); err != nil {
-			t.Error(err)
-		}
-		items.Index(n).Set(o)
+	if err := it.Err(); err != nil {
+		t.Error(err)
 	}
 	return items.Interface()
 }
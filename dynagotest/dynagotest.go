@@ -0,0 +1,1275 @@
+// Copyright 2016 Appittome. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package dynagotest provides an in-memory stand in for dynamoDB, so
+// code using dynaGo can be tested without DynamoDB Local or an AWS
+// account.
+//
+// DB implements the dynamodbiface.DynamoDBAPI operations dynaGo makes:
+// table management (CreateTable, DescribeTable, ListTables, DeleteTable,
+// UpdateTable, UpdateTimeToLive, DescribeTimeToLive), item operations (PutItem,
+// GetItem, UpdateItem, DeleteItem), Query, Scan, the batch operations
+// and transactions.  Condition, filter, key condition, update and
+// projection expressions are evaluated.  Calling any other operation
+// panics.
+//
+// Tables are ACTIVE as soon as they are created (unless StatusDelay is
+// set), reads are always consistent, and capacity is never consumed.
+package dynagotest
+
+import (
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// dynamoDB request limits
+const (
+	batchWriteLimit = 25
+	batchGetLimit   = 100
+	txLimit         = 100
+	listTablesLimit = 100
+)
+
+// DB is an in-memory dynamoDB.  The zero value is not usable, use New.
+type DB struct {
+	// panics for operations that are not implemented
+	dynamodbiface.DynamoDBAPI
+
+	// when greater than zero, BatchGetItem and BatchWriteItem process at
+	// most this many requests per call and return the rest unprocessed,
+	// to exercise retries
+	MaxBatchItems int
+
+	// when greater than zero, tables and indexes that are created,
+	// updated or deleted keep their CREATING, UPDATING or DELETING
+	// status for this many DescribeTable calls, to exercise waiting.
+	// Like dynamoDB, UpdateTable refuses a table that is not ACTIVE.
+	StatusDelay int
+
+	mu     sync.Mutex
+	tables map[string]*table
+}
+
+// New returns an empty DB.
+func New() *DB {
+	return &DB{tables: make(map[string]*table)}
+}
+
+var _ dynamodbiface.DynamoDBAPI = (*DB)(nil)
+
+type table struct {
+	desc *dynamodb.TableDescription
+	ttl  *dynamodb.TimeToLiveDescription
+	// the DescribeTable calls left before a status change settles
+	pending int
+	hash    string
+	rng     string
+	items   map[string]item
+}
+
+// the key string of it in t, "" if it lacks a key attribute
+func (t *table) key(it item) string {
+	return keyString(it, t.hash, t.rng)
+}
+
+func keyString(it item, hash, rng string) string {
+	h, ok := it[hash]
+	if !ok {
+		return ""
+	}
+	s := scalarString(h)
+	if rng != "" {
+		r, ok := it[rng]
+		if !ok {
+			return ""
+		}
+		s += "\x00" + scalarString(r)
+	}
+	return s
+}
+
+func scalarString(v *dynamodb.AttributeValue) string {
+	switch {
+	case v.S != nil:
+		return "S" + *v.S
+	case v.N != nil:
+		return "N" + number(v).Text('g', -1)
+	}
+	return "B" + string(v.B)
+}
+
+// a string identifying the key k whatever its schema
+func itemString(k item) string {
+	ss := make([]string, 0, len(k))
+	for _, n := range sortedKeys(map[string]*dynamodb.AttributeValue(k)) {
+		ss = append(ss, n+"="+scalarString(k[n]))
+	}
+	return strings.Join(ss, "\x00")
+}
+
+// starts a status change of the table, settled at once without a delay
+func (db *DB) change(t *table) {
+	t.pending = db.StatusDelay
+	if t.pending == 0 {
+		db.settle(t)
+	}
+}
+
+// completes the status changes of t.  must be called with db.mu held
+func (db *DB) settle(t *table) {
+	if aws.StringValue(t.desc.TableStatus) == dynamodb.TableStatusDeleting {
+		delete(db.tables, *t.desc.TableName)
+		return
+	}
+	t.desc.TableStatus = aws.String(dynamodb.TableStatusActive)
+	gsis := t.desc.GlobalSecondaryIndexes[:0]
+	for _, gsi := range t.desc.GlobalSecondaryIndexes {
+		if aws.StringValue(gsi.IndexStatus) == dynamodb.IndexStatusDeleting {
+			continue
+		}
+		gsi.IndexStatus = aws.String(dynamodb.IndexStatusActive)
+		gsi.Backfilling = aws.Bool(false)
+		gsis = append(gsis, gsi)
+	}
+	t.desc.GlobalSecondaryIndexes = gsis
+	if len(gsis) == 0 {
+		t.desc.GlobalSecondaryIndexes = nil
+	}
+}
+
+func (t *table) keyOf(it item) item {
+	k := item{t.hash: it[t.hash]}
+	if t.rng != "" {
+		k[t.rng] = it[t.rng]
+	}
+	return k
+}
+
+//-- ERRORS --//
+
+func validationError(format string, args ...interface{}) error {
+	return awserr.New("ValidationException", fmt.Sprintf(format, args...), nil)
+}
+
+func notFound(tn string) error {
+	return &dynamodb.ResourceNotFoundException{Message_: aws.String("Requested resource not found: Table: " + tn + " not found")}
+}
+
+func conditionFailed() error {
+	return &dynamodb.ConditionalCheckFailedException{Message_: aws.String("The conditional request failed")}
+}
+
+// checks a request before it is served, as the sdk and service would.
+// Like the sdk, operations return an empty output along with any error.
+func start(ctx aws.Context, in request.Validator) error {
+	if ctx != nil {
+		if err := ctx.Err(); err != nil {
+			return awserr.New(request.CanceledErrorCode, "request context canceled", err)
+		}
+	}
+	if reflect.ValueOf(in).IsNil() {
+		return validationError("missing required input")
+	}
+	return in.Validate()
+}
+
+// must be called with db.mu held
+func (db *DB) table(tn *string) (*table, error) {
+	t, ok := db.tables[aws.StringValue(tn)]
+	if !ok {
+		return nil, notFound(aws.StringValue(tn))
+	}
+	return t, nil
+}
+
+//-- TABLES --//
+
+func (db *DB) CreateTable(in *dynamodb.CreateTableInput) (*dynamodb.CreateTableOutput, error) {
+	return db.CreateTableWithContext(nil, in)
+}
+
+func (db *DB) CreateTableWithContext(ctx aws.Context, in *dynamodb.CreateTableInput, _ ...request.Option) (*dynamodb.CreateTableOutput, error) {
+	if err := start(ctx, in); err != nil {
+		return &dynamodb.CreateTableOutput{}, err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	tn := aws.StringValue(in.TableName)
+	if _, ok := db.tables[tn]; ok {
+		return &dynamodb.CreateTableOutput{}, &dynamodb.ResourceInUseException{Message_: aws.String("Table already exists: " + tn)}
+	}
+	t := &table{items: make(map[string]item)}
+	for _, k := range in.KeySchema {
+		if *k.KeyType == dynamodb.KeyTypeHash {
+			t.hash = *k.AttributeName
+		} else {
+			t.rng = *k.AttributeName
+		}
+	}
+	if t.hash == "" {
+		return &dynamodb.CreateTableOutput{}, validationError("no HASH key in KeySchema of %s", tn)
+	}
+	billing := aws.StringValue(in.BillingMode)
+	if billing == "" {
+		billing = dynamodb.BillingModeProvisioned
+	}
+	t.desc = &dynamodb.TableDescription{
+		TableName:            in.TableName,
+		TableStatus:          aws.String(dynamodb.TableStatusCreating),
+		KeySchema:            in.KeySchema,
+		AttributeDefinitions: in.AttributeDefinitions,
+		BillingModeSummary:   &dynamodb.BillingModeSummary{BillingMode: aws.String(billing)},
+		StreamSpecification:  in.StreamSpecification,
+		ItemCount:            aws.Int64(0),
+	}
+	if in.ProvisionedThroughput != nil {
+		t.desc.ProvisionedThroughput = &dynamodb.ProvisionedThroughputDescription{
+			ReadCapacityUnits:  in.ProvisionedThroughput.ReadCapacityUnits,
+			WriteCapacityUnits: in.ProvisionedThroughput.WriteCapacityUnits,
+		}
+	}
+	for _, gsi := range in.GlobalSecondaryIndexes {
+		t.desc.GlobalSecondaryIndexes = append(t.desc.GlobalSecondaryIndexes, &dynamodb.GlobalSecondaryIndexDescription{
+			IndexName:   gsi.IndexName,
+			IndexStatus: aws.String(dynamodb.IndexStatusCreating),
+			KeySchema:   gsi.KeySchema,
+			Projection:  gsi.Projection,
+			Backfilling: aws.Bool(true),
+		})
+	}
+	db.tables[tn] = t
+	db.change(t)
+	return &dynamodb.CreateTableOutput{TableDescription: t.desc}, nil
+}
+
+func (db *DB) DescribeTable(in *dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+	return db.DescribeTableWithContext(nil, in)
+}
+
+func (db *DB) DescribeTableWithContext(ctx aws.Context, in *dynamodb.DescribeTableInput, _ ...request.Option) (*dynamodb.DescribeTableOutput, error) {
+	if err := start(ctx, in); err != nil {
+		return &dynamodb.DescribeTableOutput{}, err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	t, err := db.table(in.TableName)
+	if err != nil {
+		return &dynamodb.DescribeTableOutput{}, err
+	}
+	if t.pending == 0 {
+		db.settle(t)
+		if t, err = db.table(in.TableName); err != nil {
+			return &dynamodb.DescribeTableOutput{}, err
+		}
+	} else {
+		t.pending--
+	}
+	t.desc.ItemCount = aws.Int64(int64(len(t.items)))
+	return &dynamodb.DescribeTableOutput{Table: t.desc}, nil
+}
+
+func (db *DB) DeleteTable(in *dynamodb.DeleteTableInput) (*dynamodb.DeleteTableOutput, error) {
+	return db.DeleteTableWithContext(nil, in)
+}
+
+func (db *DB) DeleteTableWithContext(ctx aws.Context, in *dynamodb.DeleteTableInput, _ ...request.Option) (*dynamodb.DeleteTableOutput, error) {
+	if err := start(ctx, in); err != nil {
+		return &dynamodb.DeleteTableOutput{}, err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	t, err := db.table(in.TableName)
+	if err != nil {
+		return &dynamodb.DeleteTableOutput{}, err
+	}
+	t.desc.TableStatus = aws.String(dynamodb.TableStatusDeleting)
+	out := &dynamodb.DeleteTableOutput{TableDescription: t.desc}
+	db.change(t)
+	return out, nil
+}
+
+func (db *DB) UpdateTable(in *dynamodb.UpdateTableInput) (*dynamodb.UpdateTableOutput, error) {
+	return db.UpdateTableWithContext(nil, in)
+}
+
+// UpdateTableWithContext creates or deletes a global secondary index,
+// one per call as dynamoDB allows, and changes the billing mode,
+// provisioned throughput and stream of the table.
+func (db *DB) UpdateTableWithContext(ctx aws.Context, in *dynamodb.UpdateTableInput, _ ...request.Option) (*dynamodb.UpdateTableOutput, error) {
+	if err := start(ctx, in); err != nil {
+		return &dynamodb.UpdateTableOutput{}, err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	t, err := db.table(in.TableName)
+	if err != nil {
+		return &dynamodb.UpdateTableOutput{}, err
+	}
+	if aws.StringValue(t.desc.TableStatus) != dynamodb.TableStatusActive || t.pending > 0 {
+		return &dynamodb.UpdateTableOutput{}, &dynamodb.ResourceInUseException{
+			Message_: aws.String("Attempt to change a resource which is still in use: " + *in.TableName)}
+	}
+	if len(in.GlobalSecondaryIndexUpdates) > 1 {
+		return &dynamodb.UpdateTableOutput{}, validationError("only one global secondary index can be created or deleted per UpdateTable")
+	}
+	ads := t.desc.AttributeDefinitions
+	for _, ad := range in.AttributeDefinitions {
+		if attributeType(ads, *ad.AttributeName) == "" {
+			ads = append(ads, ad)
+		}
+	}
+	for _, u := range in.GlobalSecondaryIndexUpdates {
+		switch {
+		case u.Create != nil:
+			if index(t, *u.Create.IndexName) != nil {
+				return &dynamodb.UpdateTableOutput{}, validationError("index %s already exists", *u.Create.IndexName)
+			}
+			for _, k := range u.Create.KeySchema {
+				if attributeType(ads, *k.AttributeName) == "" {
+					return &dynamodb.UpdateTableOutput{}, validationError("no AttributeDefinition for %s", *k.AttributeName)
+				}
+			}
+			t.desc.GlobalSecondaryIndexes = append(t.desc.GlobalSecondaryIndexes, &dynamodb.GlobalSecondaryIndexDescription{
+				IndexName:   u.Create.IndexName,
+				IndexStatus: aws.String(dynamodb.IndexStatusCreating),
+				KeySchema:   u.Create.KeySchema,
+				Projection:  u.Create.Projection,
+				Backfilling: aws.Bool(true),
+			})
+		case u.Delete != nil:
+			gsi := index(t, *u.Delete.IndexName)
+			if gsi == nil {
+				return &dynamodb.UpdateTableOutput{}, &dynamodb.ResourceNotFoundException{
+					Message_: aws.String("Requested resource not found: Index: " + *u.Delete.IndexName + " not found")}
+			}
+			gsi.IndexStatus = aws.String(dynamodb.IndexStatusDeleting)
+		default:
+			return &dynamodb.UpdateTableOutput{}, validationError("dynagotest: only index creation and deletion are supported")
+		}
+	}
+	t.desc.AttributeDefinitions = ads
+	if in.BillingMode != nil {
+		t.desc.BillingModeSummary = &dynamodb.BillingModeSummary{BillingMode: in.BillingMode}
+	}
+	if in.ProvisionedThroughput != nil {
+		t.desc.ProvisionedThroughput = &dynamodb.ProvisionedThroughputDescription{
+			ReadCapacityUnits:  in.ProvisionedThroughput.ReadCapacityUnits,
+			WriteCapacityUnits: in.ProvisionedThroughput.WriteCapacityUnits,
+		}
+	}
+	if in.StreamSpecification != nil {
+		t.desc.StreamSpecification = in.StreamSpecification
+	}
+	t.desc.TableStatus = aws.String(dynamodb.TableStatusUpdating)
+	out := &dynamodb.UpdateTableOutput{TableDescription: t.desc}
+	db.change(t)
+	return out, nil
+}
+
+// the index n of t, nil if there is none
+func index(t *table, n string) *dynamodb.GlobalSecondaryIndexDescription {
+	for _, gsi := range t.desc.GlobalSecondaryIndexes {
+		if *gsi.IndexName == n {
+			return gsi
+		}
+	}
+	return nil
+}
+
+// the type of attribute n, "" if it is not defined
+func attributeType(ads []*dynamodb.AttributeDefinition, n string) string {
+	for _, ad := range ads {
+		if *ad.AttributeName == n {
+			return *ad.AttributeType
+		}
+	}
+	return ""
+}
+
+func (db *DB) ListTables(in *dynamodb.ListTablesInput) (*dynamodb.ListTablesOutput, error) {
+	return db.ListTablesWithContext(nil, in)
+}
+
+func (db *DB) ListTablesWithContext(ctx aws.Context, in *dynamodb.ListTablesInput, _ ...request.Option) (*dynamodb.ListTablesOutput, error) {
+	if err := start(ctx, in); err != nil {
+		return &dynamodb.ListTablesOutput{}, err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	tns := make([]string, 0, len(db.tables))
+	for tn := range db.tables {
+		if tn > aws.StringValue(in.ExclusiveStartTableName) {
+			tns = append(tns, tn)
+		}
+	}
+	sort.Strings(tns)
+	limit := int(aws.Int64Value(in.Limit))
+	if limit <= 0 || limit > listTablesLimit {
+		limit = listTablesLimit
+	}
+	out := &dynamodb.ListTablesOutput{}
+	if len(tns) > limit {
+		tns = tns[:limit]
+		out.LastEvaluatedTableName = aws.String(tns[limit-1])
+	}
+	out.TableNames = aws.StringSlice(tns)
+	return out, nil
+}
+
+func (db *DB) ListTablesPages(in *dynamodb.ListTablesInput, fn func(*dynamodb.ListTablesOutput, bool) bool) error {
+	return db.ListTablesPagesWithContext(nil, in, fn)
+}
+
+func (db *DB) ListTablesPagesWithContext(ctx aws.Context, in *dynamodb.ListTablesInput, fn func(*dynamodb.ListTablesOutput, bool) bool, _ ...request.Option) error {
+	params := *in
+	for {
+		out, err := db.ListTablesWithContext(ctx, &params)
+		if err != nil {
+			return err
+		}
+		last := out.LastEvaluatedTableName == nil
+		if !fn(out, last) || last {
+			return nil
+		}
+		params.ExclusiveStartTableName = out.LastEvaluatedTableName
+	}
+}
+
+func (db *DB) UpdateTimeToLive(in *dynamodb.UpdateTimeToLiveInput) (*dynamodb.UpdateTimeToLiveOutput, error) {
+	return db.UpdateTimeToLiveWithContext(nil, in)
+}
+
+func (db *DB) UpdateTimeToLiveWithContext(ctx aws.Context, in *dynamodb.UpdateTimeToLiveInput, _ ...request.Option) (*dynamodb.UpdateTimeToLiveOutput, error) {
+	if err := start(ctx, in); err != nil {
+		return &dynamodb.UpdateTimeToLiveOutput{}, err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	t, err := db.table(in.TableName)
+	if err != nil {
+		return &dynamodb.UpdateTimeToLiveOutput{}, err
+	}
+	status := dynamodb.TimeToLiveStatusDisabled
+	if aws.BoolValue(in.TimeToLiveSpecification.Enabled) {
+		status = dynamodb.TimeToLiveStatusEnabled
+	}
+	t.ttl = &dynamodb.TimeToLiveDescription{
+		AttributeName:    in.TimeToLiveSpecification.AttributeName,
+		TimeToLiveStatus: aws.String(status),
+	}
+	return &dynamodb.UpdateTimeToLiveOutput{TimeToLiveSpecification: in.TimeToLiveSpecification}, nil
+}
+
+func (db *DB) DescribeTimeToLive(in *dynamodb.DescribeTimeToLiveInput) (*dynamodb.DescribeTimeToLiveOutput, error) {
+	return db.DescribeTimeToLiveWithContext(nil, in)
+}
+
+func (db *DB) DescribeTimeToLiveWithContext(ctx aws.Context, in *dynamodb.DescribeTimeToLiveInput, _ ...request.Option) (*dynamodb.DescribeTimeToLiveOutput, error) {
+	if err := start(ctx, in); err != nil {
+		return &dynamodb.DescribeTimeToLiveOutput{}, err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	t, err := db.table(in.TableName)
+	if err != nil {
+		return &dynamodb.DescribeTimeToLiveOutput{}, err
+	}
+	ttl := t.ttl
+	if ttl == nil {
+		ttl = &dynamodb.TimeToLiveDescription{TimeToLiveStatus: aws.String(dynamodb.TimeToLiveStatusDisabled)}
+	}
+	return &dynamodb.DescribeTimeToLiveOutput{TimeToLiveDescription: ttl}, nil
+}
+
+//-- ITEMS --//
+
+// a write checked against the stored item by its condition
+type write struct {
+	t       *table
+	key     string
+	cond    *string
+	names   map[string]*string
+	values  map[string]*dynamodb.AttributeValue
+	apply   func(old item) (item, error) // nil item deletes
+	onlyChk bool
+}
+
+// checks the condition of w against the stored item, must be called with
+// db.mu held
+func (w *write) check() error {
+	if w.cond == nil {
+		return nil
+	}
+	c, err := parseCondition(*w.cond, w.names, w.values)
+	if err != nil {
+		return err
+	}
+	old := w.t.items[w.key]
+	if old == nil {
+		old = item{}
+	}
+	ok, err := c(old)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return conditionFailed()
+	}
+	return nil
+}
+
+// applies w, returning the old and new item
+func (w *write) commit() (item, item, error) {
+	old := w.t.items[w.key]
+	if w.onlyChk {
+		return old, old, nil
+	}
+	var base item
+	if old != nil {
+		base = old
+	} else {
+		base = item{}
+	}
+	nw, err := w.apply(base)
+	if err != nil {
+		return nil, nil, err
+	}
+	if nw == nil {
+		delete(w.t.items, w.key)
+	} else {
+		w.t.items[w.key] = copyItem(nw)
+	}
+	return old, nw, nil
+}
+
+func (db *DB) putWrite(tn *string, it item, cond *string, names map[string]*string, values map[string]*dynamodb.AttributeValue) (*write, error) {
+	t, err := db.table(tn)
+	if err != nil {
+		return nil, err
+	}
+	k := t.key(it)
+	if k == "" {
+		return nil, validationError("one of the required keys was not given a value")
+	}
+	return &write{t: t, key: k, cond: cond, names: names, values: values,
+		apply: func(item) (item, error) { return it, nil }}, nil
+}
+
+func (db *DB) deleteWrite(tn *string, k item, cond *string, names map[string]*string, values map[string]*dynamodb.AttributeValue) (*write, error) {
+	t, err := db.table(tn)
+	if err != nil {
+		return nil, err
+	}
+	ks := t.key(k)
+	if ks == "" || len(k) != len(t.keyOf(k)) {
+		return nil, validationError("the provided key element does not match the schema")
+	}
+	return &write{t: t, key: ks, cond: cond, names: names, values: values,
+		apply: func(item) (item, error) { return nil, nil }}, nil
+}
+
+func (db *DB) updateWrite(tn *string, k item, ue, cond *string, names map[string]*string, values map[string]*dynamodb.AttributeValue, touched *[]string) (*write, error) {
+	t, err := db.table(tn)
+	if err != nil {
+		return nil, err
+	}
+	ks := t.key(k)
+	if ks == "" || len(k) != len(t.keyOf(k)) {
+		return nil, validationError("the provided key element does not match the schema")
+	}
+	var u *update
+	if ue != nil {
+		if u, err = parseUpdate(*ue, names, values); err != nil {
+			return nil, err
+		}
+	}
+	return &write{t: t, key: ks, cond: cond, names: names, values: values,
+		apply: func(old item) (item, error) {
+			nw := copyItem(old)
+			for n, v := range k {
+				nw[n] = v
+			}
+			if u == nil {
+				return nw, nil
+			}
+			nw, ns, err := u.apply(nw)
+			if err == nil && touched != nil {
+				*touched = ns
+			}
+			for _, n := range ns {
+				if n == t.hash || n == t.rng {
+					return nil, validationError("cannot update key attribute %s", n)
+				}
+			}
+			return nw, err
+		}}, nil
+}
+
+func (db *DB) PutItem(in *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	return db.PutItemWithContext(nil, in)
+}
+
+func (db *DB) PutItemWithContext(ctx aws.Context, in *dynamodb.PutItemInput, _ ...request.Option) (*dynamodb.PutItemOutput, error) {
+	if err := start(ctx, in); err != nil {
+		return &dynamodb.PutItemOutput{}, err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	w, err := db.putWrite(in.TableName, in.Item, in.ConditionExpression, in.ExpressionAttributeNames, in.ExpressionAttributeValues)
+	if err != nil {
+		return &dynamodb.PutItemOutput{}, err
+	}
+	if err := w.check(); err != nil {
+		return &dynamodb.PutItemOutput{}, err
+	}
+	old, _, err := w.commit()
+	if err != nil {
+		return &dynamodb.PutItemOutput{}, err
+	}
+	out := &dynamodb.PutItemOutput{}
+	if aws.StringValue(in.ReturnValues) == dynamodb.ReturnValueAllOld && old != nil {
+		out.Attributes = copyItem(old)
+	}
+	return out, nil
+}
+
+func (db *DB) GetItem(in *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	return db.GetItemWithContext(nil, in)
+}
+
+func (db *DB) GetItemWithContext(ctx aws.Context, in *dynamodb.GetItemInput, _ ...request.Option) (*dynamodb.GetItemOutput, error) {
+	if err := start(ctx, in); err != nil {
+		return &dynamodb.GetItemOutput{}, err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	it, err := db.get(in.TableName, in.Key, in.ProjectionExpression, in.ExpressionAttributeNames)
+	if err != nil {
+		return &dynamodb.GetItemOutput{}, err
+	}
+	return &dynamodb.GetItemOutput{Item: it}, nil
+}
+
+// a copy of the item with key k, nil if there is none
+func (db *DB) get(tn *string, k item, pe *string, names map[string]*string) (item, error) {
+	t, err := db.table(tn)
+	if err != nil {
+		return nil, err
+	}
+	ks := t.key(k)
+	if ks == "" || len(k) != len(t.keyOf(k)) {
+		return nil, validationError("the provided key element does not match the schema")
+	}
+	it, ok := t.items[ks]
+	if !ok {
+		return nil, nil
+	}
+	var ps []path
+	if pe != nil {
+		if ps, err = parseProjection(*pe, names); err != nil {
+			return nil, err
+		}
+	}
+	return copyItem(project(it, ps)), nil
+}
+
+func (db *DB) DeleteItem(in *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) {
+	return db.DeleteItemWithContext(nil, in)
+}
+
+func (db *DB) DeleteItemWithContext(ctx aws.Context, in *dynamodb.DeleteItemInput, _ ...request.Option) (*dynamodb.DeleteItemOutput, error) {
+	if err := start(ctx, in); err != nil {
+		return &dynamodb.DeleteItemOutput{}, err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	w, err := db.deleteWrite(in.TableName, in.Key, in.ConditionExpression, in.ExpressionAttributeNames, in.ExpressionAttributeValues)
+	if err != nil {
+		return &dynamodb.DeleteItemOutput{}, err
+	}
+	if err := w.check(); err != nil {
+		return &dynamodb.DeleteItemOutput{}, err
+	}
+	old, _, err := w.commit()
+	if err != nil {
+		return &dynamodb.DeleteItemOutput{}, err
+	}
+	out := &dynamodb.DeleteItemOutput{}
+	if aws.StringValue(in.ReturnValues) == dynamodb.ReturnValueAllOld && old != nil {
+		out.Attributes = copyItem(old)
+	}
+	return out, nil
+}
+
+func (db *DB) UpdateItem(in *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+	return db.UpdateItemWithContext(nil, in)
+}
+
+func (db *DB) UpdateItemWithContext(ctx aws.Context, in *dynamodb.UpdateItemInput, _ ...request.Option) (*dynamodb.UpdateItemOutput, error) {
+	if err := start(ctx, in); err != nil {
+		return &dynamodb.UpdateItemOutput{}, err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var touched []string
+	w, err := db.updateWrite(in.TableName, in.Key, in.UpdateExpression, in.ConditionExpression,
+		in.ExpressionAttributeNames, in.ExpressionAttributeValues, &touched)
+	if err != nil {
+		return &dynamodb.UpdateItemOutput{}, err
+	}
+	if err := w.check(); err != nil {
+		return &dynamodb.UpdateItemOutput{}, err
+	}
+	old, nw, err := w.commit()
+	if err != nil {
+		return &dynamodb.UpdateItemOutput{}, err
+	}
+	out := &dynamodb.UpdateItemOutput{}
+	switch aws.StringValue(in.ReturnValues) {
+	case dynamodb.ReturnValueAllOld:
+		if old != nil {
+			out.Attributes = copyItem(old)
+		}
+	case dynamodb.ReturnValueAllNew:
+		out.Attributes = copyItem(nw)
+	case dynamodb.ReturnValueUpdatedOld:
+		if old != nil {
+			out.Attributes = copyItem(project(old, names(touched)))
+		}
+	case dynamodb.ReturnValueUpdatedNew:
+		out.Attributes = copyItem(project(nw, names(touched)))
+	}
+	return out, nil
+}
+
+func names(ns []string) []path {
+	ps := make([]path, 0, len(ns))
+	for _, n := range ns {
+		ps = append(ps, path{n})
+	}
+	return ps
+}
+
+//-- QUERY & SCAN --//
+
+// the items of the table, or of one of its indexes, in key order
+type view struct {
+	t    *table
+	hash string
+	rng  string
+}
+
+// must be called with db.mu held
+func (db *DB) view(tn, index *string) (*view, error) {
+	t, err := db.table(tn)
+	if err != nil {
+		return nil, err
+	}
+	if index == nil {
+		return &view{t, t.hash, t.rng}, nil
+	}
+	for _, gsi := range t.desc.GlobalSecondaryIndexes {
+		if *gsi.IndexName != *index {
+			continue
+		}
+		v := &view{t: t}
+		for _, k := range gsi.KeySchema {
+			if *k.KeyType == dynamodb.KeyTypeHash {
+				v.hash = *k.AttributeName
+			} else {
+				v.rng = *k.AttributeName
+			}
+		}
+		return v, nil
+	}
+	return nil, validationError("the table does not have the specified index: %s", *index)
+}
+
+// orders items by the view key, then the table key
+func (v *view) less(a, b item) bool {
+	for _, n := range []string{v.hash, v.rng, v.t.hash, v.t.rng} {
+		if n == "" {
+			continue
+		}
+		av, bv := a[n], b[n]
+		if av == nil || bv == nil {
+			continue
+		}
+		if c, _ := compare(av, bv); c != 0 {
+			return c < 0
+		}
+	}
+	return false
+}
+
+// the items of the view (those with its key attributes), in order
+func (v *view) items(forward bool) []item {
+	its := make([]item, 0, len(v.t.items))
+	for _, it := range v.t.items {
+		if keyString(it, v.hash, v.rng) != "" {
+			its = append(its, it)
+		}
+	}
+	sort.Slice(its, func(i, j int) bool { return v.less(its[i], its[j]) })
+	if !forward {
+		for i, j := 0, len(its)-1; i < j; i, j = i+1, j-1 {
+			its[i], its[j] = its[j], its[i]
+		}
+	}
+	return its
+}
+
+// the key dynamoDB would return as LastEvaluatedKey for it
+func (v *view) lastKey(it item) item {
+	k := v.t.keyOf(it)
+	k[v.hash] = it[v.hash]
+	if v.rng != "" {
+		k[v.rng] = it[v.rng]
+	}
+	return copyItem(k)
+}
+
+type readParams struct {
+	keyCond    condition
+	filter     condition
+	projection []path
+	start      item
+	limit      int
+	forward    bool
+	segment    int
+	segments   int
+}
+
+type readResult struct {
+	items   []item
+	scanned int
+	last    item
+}
+
+func (v *view) read(p readParams) readResult {
+	its := v.items(p.forward)
+	r := readResult{items: make([]item, 0)}
+	started := p.start == nil
+	var prev item
+	for _, it := range its {
+		if !started {
+			// skip up to and including the start key
+			if p.forward && !v.less(p.start, it) || !p.forward && !v.less(it, p.start) {
+				continue
+			}
+			started = true
+		}
+		if p.segments > 1 && segment(v.t.key(it), p.segments) != p.segment {
+			continue
+		}
+		if p.keyCond != nil {
+			if ok, _ := p.keyCond(it); !ok {
+				continue
+			}
+		}
+		if p.limit > 0 && r.scanned == p.limit {
+			r.last = v.lastKey(prev)
+			break
+		}
+		r.scanned++
+		prev = it
+		if p.filter != nil {
+			if ok, _ := p.filter(it); !ok {
+				continue
+			}
+		}
+		r.items = append(r.items, copyItem(project(it, p.projection)))
+	}
+	return r
+}
+
+func segment(k string, total int) int {
+	h := fnv.New32a()
+	h.Write([]byte(k))
+	return int(h.Sum32() % uint32(total))
+}
+
+func (db *DB) Query(in *dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+	return db.QueryWithContext(nil, in)
+}
+
+func (db *DB) QueryWithContext(ctx aws.Context, in *dynamodb.QueryInput, _ ...request.Option) (*dynamodb.QueryOutput, error) {
+	if err := start(ctx, in); err != nil {
+		return &dynamodb.QueryOutput{}, err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	v, err := db.view(in.TableName, in.IndexName)
+	if err != nil {
+		return &dynamodb.QueryOutput{}, err
+	}
+	if in.KeyConditionExpression == nil {
+		return &dynamodb.QueryOutput{}, validationError("KeyConditionExpression is required")
+	}
+	p := readParams{
+		start:   in.ExclusiveStartKey,
+		limit:   int(aws.Int64Value(in.Limit)),
+		forward: in.ScanIndexForward == nil || *in.ScanIndexForward,
+	}
+	if p.keyCond, err = parseCondition(*in.KeyConditionExpression, in.ExpressionAttributeNames, in.ExpressionAttributeValues); err != nil {
+		return &dynamodb.QueryOutput{}, err
+	}
+	if in.FilterExpression != nil {
+		if p.filter, err = parseCondition(*in.FilterExpression, in.ExpressionAttributeNames, in.ExpressionAttributeValues); err != nil {
+			return &dynamodb.QueryOutput{}, err
+		}
+	}
+	if in.ProjectionExpression != nil {
+		if p.projection, err = parseProjection(*in.ProjectionExpression, in.ExpressionAttributeNames); err != nil {
+			return &dynamodb.QueryOutput{}, err
+		}
+	}
+	r := v.read(p)
+	out := &dynamodb.QueryOutput{
+		Count:            aws.Int64(int64(len(r.items))),
+		ScannedCount:     aws.Int64(int64(r.scanned)),
+		LastEvaluatedKey: r.last,
+	}
+	if aws.StringValue(in.Select) != dynamodb.SelectCount {
+		out.Items = toMaps(r.items)
+	}
+	return out, nil
+}
+
+func (db *DB) Scan(in *dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
+	return db.ScanWithContext(nil, in)
+}
+
+func (db *DB) ScanWithContext(ctx aws.Context, in *dynamodb.ScanInput, _ ...request.Option) (*dynamodb.ScanOutput, error) {
+	if err := start(ctx, in); err != nil {
+		return &dynamodb.ScanOutput{}, err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	v, err := db.view(in.TableName, in.IndexName)
+	if err != nil {
+		return &dynamodb.ScanOutput{}, err
+	}
+	p := readParams{
+		start:    in.ExclusiveStartKey,
+		limit:    int(aws.Int64Value(in.Limit)),
+		forward:  true,
+		segment:  int(aws.Int64Value(in.Segment)),
+		segments: int(aws.Int64Value(in.TotalSegments)),
+	}
+	if p.segments > 0 && (p.segment < 0 || p.segment >= p.segments) {
+		return &dynamodb.ScanOutput{}, validationError("Segment must be less than TotalSegments")
+	}
+	if in.FilterExpression != nil {
+		if p.filter, err = parseCondition(*in.FilterExpression, in.ExpressionAttributeNames, in.ExpressionAttributeValues); err != nil {
+			return &dynamodb.ScanOutput{}, err
+		}
+	}
+	if in.ProjectionExpression != nil {
+		if p.projection, err = parseProjection(*in.ProjectionExpression, in.ExpressionAttributeNames); err != nil {
+			return &dynamodb.ScanOutput{}, err
+		}
+	}
+	r := v.read(p)
+	out := &dynamodb.ScanOutput{
+		Count:            aws.Int64(int64(len(r.items))),
+		ScannedCount:     aws.Int64(int64(r.scanned)),
+		LastEvaluatedKey: r.last,
+	}
+	if aws.StringValue(in.Select) != dynamodb.SelectCount {
+		out.Items = toMaps(r.items)
+	}
+	return out, nil
+}
+
+//-- BATCHES --//
+
+func (db *DB) BatchGetItem(in *dynamodb.BatchGetItemInput) (*dynamodb.BatchGetItemOutput, error) {
+	return db.BatchGetItemWithContext(nil, in)
+}
+
+func (db *DB) BatchGetItemWithContext(ctx aws.Context, in *dynamodb.BatchGetItemInput, _ ...request.Option) (*dynamodb.BatchGetItemOutput, error) {
+	if err := start(ctx, in); err != nil {
+		return &dynamodb.BatchGetItemOutput{}, err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	n := 0
+	for _, ka := range in.RequestItems {
+		n += len(ka.Keys)
+	}
+	if n > batchGetLimit {
+		return &dynamodb.BatchGetItemOutput{}, validationError("too many items requested for the BatchGetItem call")
+	}
+	out := &dynamodb.BatchGetItemOutput{
+		Responses:       make(map[string][]map[string]*dynamodb.AttributeValue),
+		UnprocessedKeys: make(map[string]*dynamodb.KeysAndAttributes),
+	}
+	processed := 0
+	for _, tn := range sortedKeys(in.RequestItems) {
+		ka := in.RequestItems[tn]
+		seen := make(map[string]bool)
+		for _, k := range ka.Keys {
+			if db.MaxBatchItems > 0 && processed == db.MaxBatchItems {
+				if _, ok := out.UnprocessedKeys[tn]; !ok {
+					c := *ka
+					c.Keys = nil
+					out.UnprocessedKeys[tn] = &c
+				}
+				out.UnprocessedKeys[tn].Keys = append(out.UnprocessedKeys[tn].Keys, k)
+				continue
+			}
+			processed++
+			ks := itemString(k)
+			if seen[ks] {
+				return &dynamodb.BatchGetItemOutput{}, validationError("provided list of item keys contains duplicates")
+			}
+			seen[ks] = true
+			it, err := db.get(aws.String(tn), k, ka.ProjectionExpression, ka.ExpressionAttributeNames)
+			if err != nil {
+				return &dynamodb.BatchGetItemOutput{}, err
+			}
+			if it != nil {
+				out.Responses[tn] = append(out.Responses[tn], it)
+			}
+		}
+	}
+	return out, nil
+}
+
+func (db *DB) BatchWriteItem(in *dynamodb.BatchWriteItemInput) (*dynamodb.BatchWriteItemOutput, error) {
+	return db.BatchWriteItemWithContext(nil, in)
+}
+
+func (db *DB) BatchWriteItemWithContext(ctx aws.Context, in *dynamodb.BatchWriteItemInput, _ ...request.Option) (*dynamodb.BatchWriteItemOutput, error) {
+	if err := start(ctx, in); err != nil {
+		return &dynamodb.BatchWriteItemOutput{}, err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	n := 0
+	for _, wrs := range in.RequestItems {
+		n += len(wrs)
+	}
+	if n > batchWriteLimit {
+		return &dynamodb.BatchWriteItemOutput{}, validationError("too many items requested for the BatchWriteItem call")
+	}
+	// validate everything before writing anything
+	ws := make([]*write, 0, n)
+	pending := make(map[string][]*dynamodb.WriteRequest)
+	seen := make(map[string]bool)
+	for _, tn := range sortedKeys(in.RequestItems) {
+		for _, wr := range in.RequestItems[tn] {
+			if db.MaxBatchItems > 0 && len(ws) == db.MaxBatchItems {
+				pending[tn] = append(pending[tn], wr)
+				continue
+			}
+			var w *write
+			var err error
+			switch {
+			case wr.PutRequest != nil:
+				w, err = db.putWrite(aws.String(tn), wr.PutRequest.Item, nil, nil, nil)
+			case wr.DeleteRequest != nil:
+				w, err = db.deleteWrite(aws.String(tn), wr.DeleteRequest.Key, nil, nil, nil)
+			default:
+				err = validationError("a WriteRequest must have a PutRequest or a DeleteRequest")
+			}
+			if err != nil {
+				return &dynamodb.BatchWriteItemOutput{}, err
+			}
+			id := tn + "\x00" + w.key
+			if seen[id] {
+				return &dynamodb.BatchWriteItemOutput{}, validationError("provided list of item keys contains duplicates")
+			}
+			seen[id] = true
+			ws = append(ws, w)
+		}
+	}
+	for _, w := range ws {
+		if _, _, err := w.commit(); err != nil {
+			return &dynamodb.BatchWriteItemOutput{}, err
+		}
+	}
+	return &dynamodb.BatchWriteItemOutput{UnprocessedItems: pending}, nil
+}
+
+//-- TRANSACTIONS --//
+
+func (db *DB) TransactWriteItems(in *dynamodb.TransactWriteItemsInput) (*dynamodb.TransactWriteItemsOutput, error) {
+	return db.TransactWriteItemsWithContext(nil, in)
+}
+
+func (db *DB) TransactWriteItemsWithContext(ctx aws.Context, in *dynamodb.TransactWriteItemsInput, _ ...request.Option) (*dynamodb.TransactWriteItemsOutput, error) {
+	if err := start(ctx, in); err != nil {
+		return &dynamodb.TransactWriteItemsOutput{}, err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if len(in.TransactItems) > txLimit {
+		return &dynamodb.TransactWriteItemsOutput{}, validationError("transactions are limited to %d items", txLimit)
+	}
+	ws := make([]*write, 0, len(in.TransactItems))
+	seen := make(map[string]bool)
+	for _, ti := range in.TransactItems {
+		var w *write
+		var err error
+		switch {
+		case ti.Put != nil:
+			p := ti.Put
+			w, err = db.putWrite(p.TableName, p.Item, p.ConditionExpression, p.ExpressionAttributeNames, p.ExpressionAttributeValues)
+		case ti.Update != nil:
+			u := ti.Update
+			w, err = db.updateWrite(u.TableName, u.Key, u.UpdateExpression, u.ConditionExpression, u.ExpressionAttributeNames, u.ExpressionAttributeValues, nil)
+		case ti.Delete != nil:
+			d := ti.Delete
+			w, err = db.deleteWrite(d.TableName, d.Key, d.ConditionExpression, d.ExpressionAttributeNames, d.ExpressionAttributeValues)
+		case ti.ConditionCheck != nil:
+			c := ti.ConditionCheck
+			w, err = db.deleteWrite(c.TableName, c.Key, c.ConditionExpression, c.ExpressionAttributeNames, c.ExpressionAttributeValues)
+			if w != nil {
+				w.onlyChk = true
+			}
+		default:
+			err = validationError("a TransactWriteItem must have exactly one operation")
+		}
+		if err != nil {
+			return &dynamodb.TransactWriteItemsOutput{}, err
+		}
+		id := *w.t.desc.TableName + "\x00" + w.key
+		if seen[id] {
+			return &dynamodb.TransactWriteItemsOutput{}, validationError("transaction request cannot include multiple operations on one item")
+		}
+		seen[id] = true
+		ws = append(ws, w)
+	}
+
+	reasons := make([]*dynamodb.CancellationReason, len(ws))
+	failed := false
+	for n, w := range ws {
+		reasons[n] = &dynamodb.CancellationReason{Code: aws.String("None")}
+		if err := w.check(); err != nil {
+			if _, ok := err.(*dynamodb.ConditionalCheckFailedException); !ok {
+				return &dynamodb.TransactWriteItemsOutput{}, err
+			}
+			reasons[n] = &dynamodb.CancellationReason{
+				Code:    aws.String("ConditionalCheckFailed"),
+				Message: aws.String("The conditional request failed"),
+			}
+			failed = true
+		}
+	}
+	if failed {
+		codes := make([]string, len(reasons))
+		for n, r := range reasons {
+			codes[n] = *r.Code
+		}
+		return &dynamodb.TransactWriteItemsOutput{}, &dynamodb.TransactionCanceledException{
+			Message_:            aws.String("Transaction cancelled, please refer cancellation reasons for specific reasons [" + strings.Join(codes, ", ") + "]"),
+			CancellationReasons: reasons,
+		}
+	}
+	for _, w := range ws {
+		if _, _, err := w.commit(); err != nil {
+			return &dynamodb.TransactWriteItemsOutput{}, err
+		}
+	}
+	return &dynamodb.TransactWriteItemsOutput{}, nil
+}
+
+func (db *DB) TransactGetItems(in *dynamodb.TransactGetItemsInput) (*dynamodb.TransactGetItemsOutput, error) {
+	return db.TransactGetItemsWithContext(nil, in)
+}
+
+func (db *DB) TransactGetItemsWithContext(ctx aws.Context, in *dynamodb.TransactGetItemsInput, _ ...request.Option) (*dynamodb.TransactGetItemsOutput, error) {
+	if err := start(ctx, in); err != nil {
+		return &dynamodb.TransactGetItemsOutput{}, err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if len(in.TransactItems) > txLimit {
+		return &dynamodb.TransactGetItemsOutput{}, validationError("transactions are limited to %d items", txLimit)
+	}
+	out := &dynamodb.TransactGetItemsOutput{}
+	for _, ti := range in.TransactItems {
+		g := ti.Get
+		it, err := db.get(g.TableName, g.Key, g.ProjectionExpression, g.ExpressionAttributeNames)
+		if err != nil {
+			return &dynamodb.TransactGetItemsOutput{}, err
+		}
+		out.Responses = append(out.Responses, &dynamodb.ItemResponse{Item: it})
+	}
+	return out, nil
+}
+
+//-- UTIL --//
+
+func copyItem(it item) item {
+	if it == nil {
+		return nil
+	}
+	out := make(item, len(it))
+	for n, v := range it {
+		out[n] = copyValue(v)
+	}
+	return out
+}
+
+func copyValue(v *dynamodb.AttributeValue) *dynamodb.AttributeValue {
+	if v == nil {
+		return nil
+	}
+	c := *v
+	if v.B != nil {
+		c.B = append([]byte{}, v.B...)
+	}
+	if v.L != nil {
+		c.L = make([]*dynamodb.AttributeValue, len(v.L))
+		for i, e := range v.L {
+			c.L[i] = copyValue(e)
+		}
+	}
+	if v.M != nil {
+		c.M = copyItem(v.M)
+	}
+	return &c
+}
+
+func toMaps(its []item) []map[string]*dynamodb.AttributeValue {
+	ms := make([]map[string]*dynamodb.AttributeValue, len(its))
+	for i, it := range its {
+		ms[i] = it
+	}
+	return ms
+}
+
+// the keys of a map in order, for deterministic iteration
+func sortedKeys(m interface{}) []string {
+	var ks []string
+	switch mm := m.(type) {
+	case map[string]*dynamodb.KeysAndAttributes:
+		for k := range mm {
+			ks = append(ks, k)
+		}
+	case map[string][]*dynamodb.WriteRequest:
+		for k := range mm {
+			ks = append(ks, k)
+		}
+	case map[string]*dynamodb.AttributeValue:
+		for k := range mm {
+			ks = append(ks, k)
+		}
+	}
+	sort.Strings(ks)
+	return ks
+}
@@ -0,0 +1,310 @@
+// Copyright 2016 Appittome. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dynagotest
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func s(v string) *dynamodb.AttributeValue { return &dynamodb.AttributeValue{S: aws.String(v)} }
+func n(v int) *dynamodb.AttributeValue {
+	return &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(v))}
+}
+
+func newTestDB(t *testing.T) *DB {
+	db := New()
+	_, err := db.CreateTable(&dynamodb.CreateTableInput{
+		TableName: aws.String("Flights"),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{AttributeName: aws.String("Origin"), AttributeType: aws.String(dynamodb.ScalarAttributeTypeS)},
+			{AttributeName: aws.String("Number"), AttributeType: aws.String(dynamodb.ScalarAttributeTypeN)},
+			{AttributeName: aws.String("Gate"), AttributeType: aws.String(dynamodb.ScalarAttributeTypeS)},
+		},
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String("Origin"), KeyType: aws.String(dynamodb.KeyTypeHash)},
+			{AttributeName: aws.String("Number"), KeyType: aws.String(dynamodb.KeyTypeRange)},
+		},
+		GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndex{{
+			IndexName: aws.String("ByGate"),
+			KeySchema: []*dynamodb.KeySchemaElement{
+				{AttributeName: aws.String("Gate"), KeyType: aws.String(dynamodb.KeyTypeHash)},
+			},
+			Projection: &dynamodb.Projection{ProjectionType: aws.String(dynamodb.ProjectionTypeAll)},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 5; i++ {
+		it := item{"Origin": s("PDX"), "Number": n(i), "Seats": n(10 * i)}
+		if i%2 == 0 {
+			it["Gate"] = s("C3")
+		}
+		if _, err := db.PutItem(&dynamodb.PutItemInput{TableName: aws.String("Flights"), Item: it}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func TestConditions(t *testing.T) {
+	db := newTestDB(t)
+	_, err := db.PutItem(&dynamodb.PutItemInput{
+		TableName:                aws.String("Flights"),
+		Item:                     item{"Origin": s("PDX"), "Number": n(1)},
+		ConditionExpression:      aws.String("attribute_not_exists(#o)"),
+		ExpressionAttributeNames: map[string]*string{"#o": aws.String("Origin")},
+	})
+	if _, ok := err.(*dynamodb.ConditionalCheckFailedException); !ok {
+		t.Errorf("expected ConditionalCheckFailedException, found %v", err)
+	}
+	_, err = db.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName:                 aws.String("Flights"),
+		Key:                       item{"Origin": s("PDX"), "Number": n(2)},
+		ConditionExpression:       aws.String("Seats BETWEEN :lo AND :hi AND NOT begins_with(Gate, :g)"),
+		ExpressionAttributeValues: item{":lo": n(15), ":hi": n(25), ":g": s("B")},
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	_, err = db.PutItem(&dynamodb.PutItemInput{
+		TableName:           aws.String("Flights"),
+		Item:                item{"Origin": s("PDX"), "Number": n(1)},
+		ConditionExpression: aws.String("Seats >"),
+	})
+	if err == nil {
+		t.Error("expected a validation error for a malformed expression")
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	db := newTestDB(t)
+	out, err := db.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName:                 aws.String("Flights"),
+		Key:                       item{"Origin": s("PDX"), "Number": n(1)},
+		UpdateExpression:          aws.String("SET Seats = Seats - :one, Crew = if_not_exists(Crew, :c) REMOVE Gate ADD Tags :t"),
+		ExpressionAttributeValues: item{":one": n(1), ":c": n(4), ":t": {SS: aws.StringSlice([]string{"red"})}},
+		ReturnValues:              aws.String(dynamodb.ReturnValueAllNew),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *out.Attributes["Seats"].N != "9" || *out.Attributes["Crew"].N != "4" || len(out.Attributes["Tags"].SS) != 1 {
+		t.Errorf("unexpected item after update: %v", out.Attributes)
+	}
+	_, err = db.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName:                 aws.String("Flights"),
+		Key:                       item{"Origin": s("PDX"), "Number": n(1)},
+		UpdateExpression:          aws.String("SET Origin = :o"),
+		ExpressionAttributeValues: item{":o": s("SEA")},
+	})
+	if err == nil {
+		t.Error("expected an error updating a key attribute")
+	}
+}
+
+func TestQueryPages(t *testing.T) {
+	db := newTestDB(t)
+	in := &dynamodb.QueryInput{
+		TableName:                 aws.String("Flights"),
+		KeyConditionExpression:    aws.String("Origin = :o AND #n > :n"),
+		FilterExpression:          aws.String("Seats <> :s"),
+		ExpressionAttributeNames:  map[string]*string{"#n": aws.String("Number")},
+		ExpressionAttributeValues: item{":o": s("PDX"), ":n": n(1), ":s": n(30)},
+		ScanIndexForward:          aws.Bool(false),
+		Limit:                     aws.Int64(2),
+	}
+	var got []string
+	for {
+		out, err := db.Query(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, it := range out.Items {
+			got = append(got, *it["Number"].N)
+		}
+		if out.LastEvaluatedKey == nil {
+			break
+		}
+		in.ExclusiveStartKey = out.LastEvaluatedKey
+	}
+	if len(got) != 3 || got[0] != "5" || got[1] != "4" || got[2] != "2" {
+		t.Errorf("unexpected query results: %v", got)
+	}
+
+	out, err := db.Query(&dynamodb.QueryInput{
+		TableName:                 aws.String("Flights"),
+		IndexName:                 aws.String("ByGate"),
+		KeyConditionExpression:    aws.String("Gate = :g"),
+		ExpressionAttributeValues: item{":g": s("C3")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *out.Count != 2 {
+		t.Errorf("expected 2 items in the index, found %d", *out.Count)
+	}
+}
+
+func TestScanSegments(t *testing.T) {
+	db := newTestDB(t)
+	total := 0
+	for seg := 0; seg < 3; seg++ {
+		out, err := db.Scan(&dynamodb.ScanInput{
+			TableName:     aws.String("Flights"),
+			Segment:       aws.Int64(int64(seg)),
+			TotalSegments: aws.Int64(3),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		total += len(out.Items)
+	}
+	if total != 5 {
+		t.Errorf("expected segments to cover 5 items, found %d", total)
+	}
+}
+
+func TestBatches(t *testing.T) {
+	db := newTestDB(t)
+	db.MaxBatchItems = 2
+	out, err := db.BatchGetItem(&dynamodb.BatchGetItemInput{
+		RequestItems: map[string]*dynamodb.KeysAndAttributes{"Flights": {Keys: []map[string]*dynamodb.AttributeValue{
+			{"Origin": s("PDX"), "Number": n(1)},
+			{"Origin": s("PDX"), "Number": n(2)},
+			{"Origin": s("PDX"), "Number": n(9)},
+		}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Responses["Flights"]) != 2 || len(out.UnprocessedKeys["Flights"].Keys) != 1 {
+		t.Errorf("unexpected batch get output: %v", out)
+	}
+	wout, err := db.BatchWriteItem(&dynamodb.BatchWriteItemInput{
+		RequestItems: map[string][]*dynamodb.WriteRequest{"Flights": {
+			{DeleteRequest: &dynamodb.DeleteRequest{Key: item{"Origin": s("PDX"), "Number": n(1)}}},
+			{DeleteRequest: &dynamodb.DeleteRequest{Key: item{"Origin": s("PDX"), "Number": n(2)}}},
+			{PutRequest: &dynamodb.PutRequest{Item: item{"Origin": s("SEA"), "Number": n(1)}}},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(wout.UnprocessedItems["Flights"]) != 1 {
+		t.Errorf("expected 1 unprocessed write, found %v", wout.UnprocessedItems)
+	}
+}
+
+func TestTransactions(t *testing.T) {
+	db := newTestDB(t)
+	in := &dynamodb.TransactWriteItemsInput{TransactItems: []*dynamodb.TransactWriteItem{
+		{Delete: &dynamodb.Delete{TableName: aws.String("Flights"), Key: item{"Origin": s("PDX"), "Number": n(3)}}},
+		{Put: &dynamodb.Put{
+			TableName:           aws.String("Flights"),
+			Item:                item{"Origin": s("PDX"), "Number": n(4)},
+			ConditionExpression: aws.String("attribute_not_exists(Origin)"),
+		}},
+	}}
+	_, err := db.TransactWriteItems(in)
+	tce, ok := err.(*dynamodb.TransactionCanceledException)
+	if !ok {
+		t.Fatalf("expected TransactionCanceledException, found %v", err)
+	}
+	if *tce.CancellationReasons[0].Code != "None" || *tce.CancellationReasons[1].Code != "ConditionalCheckFailed" {
+		t.Errorf("unexpected cancellation reasons: %v", tce.CancellationReasons)
+	}
+	got, err := db.GetItem(&dynamodb.GetItemInput{TableName: aws.String("Flights"), Key: item{"Origin": s("PDX"), "Number": n(3)}})
+	if err != nil || got.Item == nil {
+		t.Errorf("a canceled transaction must not write, found %v, %v", got, err)
+	}
+}
+
+func TestUpdateTable(t *testing.T) {
+	db := newTestDB(t)
+	db.StatusDelay = 1
+	tn := aws.String("Flights")
+	status := func() string {
+		out, err := db.DescribeTable(&dynamodb.DescribeTableInput{TableName: tn})
+		if err != nil {
+			return err.(awserr.Error).Code()
+		}
+		ss := *out.Table.TableStatus
+		for _, gsi := range out.Table.GlobalSecondaryIndexes {
+			ss += " " + *gsi.IndexName + "=" + *gsi.IndexStatus
+		}
+		return ss
+	}
+	create := &dynamodb.UpdateTableInput{
+		TableName: tn,
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{AttributeName: aws.String("Seats"), AttributeType: aws.String(dynamodb.ScalarAttributeTypeN)},
+		},
+		GlobalSecondaryIndexUpdates: []*dynamodb.GlobalSecondaryIndexUpdate{{
+			Create: &dynamodb.CreateGlobalSecondaryIndexAction{
+				IndexName: aws.String("BySeats"),
+				KeySchema: []*dynamodb.KeySchemaElement{
+					{AttributeName: aws.String("Seats"), KeyType: aws.String(dynamodb.KeyTypeHash)},
+				},
+				Projection: &dynamodb.Projection{ProjectionType: aws.String(dynamodb.ProjectionTypeAll)},
+			},
+		}},
+	}
+	if _, err := db.UpdateTable(create); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.UpdateTable(create); err == nil {
+		t.Error("expected an update of an UPDATING table to be refused")
+	}
+	if s := status(); s != "UPDATING ByGate=ACTIVE BySeats=CREATING" {
+		t.Errorf("unexpected status %s", s)
+	}
+	if s := status(); s != "ACTIVE ByGate=ACTIVE BySeats=ACTIVE" {
+		t.Errorf("unexpected status %s", s)
+	}
+	if _, err := db.UpdateTable(create); err == nil {
+		t.Error("expected an existing index to be refused")
+	}
+	out, err := db.Query(&dynamodb.QueryInput{
+		TableName:                 tn,
+		IndexName:                 aws.String("BySeats"),
+		KeyConditionExpression:    aws.String("Seats = :s"),
+		ExpressionAttributeValues: item{":s": n(30)},
+	})
+	if err != nil || len(out.Items) != 1 {
+		t.Errorf("expected the new index to be queryable, found %v, %v", out.Items, err)
+	}
+
+	_, err = db.UpdateTable(&dynamodb.UpdateTableInput{
+		TableName: tn,
+		GlobalSecondaryIndexUpdates: []*dynamodb.GlobalSecondaryIndexUpdate{
+			{Delete: &dynamodb.DeleteGlobalSecondaryIndexAction{IndexName: aws.String("ByGate")}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := status(); s != "UPDATING ByGate=DELETING BySeats=ACTIVE" {
+		t.Errorf("unexpected status %s", s)
+	}
+	if s := status(); s != "ACTIVE BySeats=ACTIVE" {
+		t.Errorf("unexpected status %s", s)
+	}
+
+	if _, err := db.DeleteTable(&dynamodb.DeleteTableInput{TableName: tn}); err != nil {
+		t.Fatal(err)
+	}
+	if s := status(); s != "DELETING BySeats=ACTIVE" {
+		t.Errorf("unexpected status %s", s)
+	}
+	if s := status(); s != dynamodb.ErrCodeResourceNotFoundException {
+		t.Errorf("expected the table to be gone, found %s", s)
+	}
+}
@@ -0,0 +1,937 @@
+// Copyright 2016 Appittome. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dynagotest
+
+import (
+	"bytes"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// This file evaluates the expression language of dynamoDB: condition,
+// filter and key condition expressions, update expressions and
+// projection expressions.  It covers what is commonly used rather than
+// the whole grammar, anything it does not understand is reported as a
+// ValidationException.
+
+type item map[string]*dynamodb.AttributeValue
+
+//-- LEXER --//
+
+type token struct {
+	kind string // "ident", "name", "value", "number" or the punctuation itself
+	text string
+}
+
+func lex(s string) ([]token, error) {
+	ts := make([]token, 0)
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '#' || c == ':' || isIdentRune(c):
+			j := i + 1
+			for j < len(s) && isIdentRune(rune(s[j])) {
+				j++
+			}
+			kind := "ident"
+			switch {
+			case c == '#':
+				kind = "name"
+			case c == ':':
+				kind = "value"
+			case unicode.IsDigit(c):
+				kind = "number"
+			}
+			ts = append(ts, token{kind, s[i:j]})
+			i = j
+		case strings.HasPrefix(s[i:], "<>") || strings.HasPrefix(s[i:], "<=") || strings.HasPrefix(s[i:], ">="):
+			ts = append(ts, token{s[i : i+2], s[i : i+2]})
+			i += 2
+		case strings.ContainsRune("()[],.=<>+-", c):
+			ts = append(ts, token{string(c), string(c)})
+			i++
+		default:
+			return nil, validationError("invalid character %q in expression", string(c))
+		}
+	}
+	return ts, nil
+}
+
+func isIdentRune(c rune) bool {
+	return c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
+
+//-- PARSER --//
+
+type parser struct {
+	ts     []token
+	pos    int
+	names  map[string]*string
+	values map[string]*dynamodb.AttributeValue
+}
+
+func newParser(s string, names map[string]*string, values map[string]*dynamodb.AttributeValue) (*parser, error) {
+	ts, err := lex(s)
+	if err != nil {
+		return nil, err
+	}
+	return &parser{ts: ts, names: names, values: values}, nil
+}
+
+func (p *parser) peek() token {
+	if p.pos < len(p.ts) {
+		return p.ts[p.pos]
+	}
+	return token{}
+}
+
+func (p *parser) next() token {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+// reports whether the next token is the keyword kw, case insensitive
+func (p *parser) isKeyword(kw string) bool {
+	t := p.peek()
+	return t.kind == "ident" && strings.EqualFold(t.text, kw)
+}
+
+func (p *parser) expect(kind string) (token, error) {
+	t := p.next()
+	if t.kind != kind {
+		return t, validationError("expected %q but found %q", kind, t.text)
+	}
+	return t, nil
+}
+
+func (p *parser) done() error {
+	if p.pos < len(p.ts) {
+		return validationError("unexpected %q in expression", p.peek().text)
+	}
+	return nil
+}
+
+// a path to an attribute, each element is a map key (string) or a list
+// index (int)
+type path []interface{}
+
+func (p *parser) path() (path, error) {
+	var ps path
+	for {
+		t := p.next()
+		switch t.kind {
+		case "ident":
+			ps = append(ps, t.text)
+		case "name":
+			n, ok := p.names[t.text]
+			if !ok {
+				return nil, validationError("undefined attribute name placeholder %s", t.text)
+			}
+			ps = append(ps, *n)
+		default:
+			return nil, validationError("expected an attribute name but found %q", t.text)
+		}
+		for p.peek().kind == "[" {
+			p.next()
+			n, err := p.expect("number")
+			if err != nil {
+				return nil, err
+			}
+			idx, _ := strconv.Atoi(n.text)
+			ps = append(ps, idx)
+			if _, err := p.expect("]"); err != nil {
+				return nil, err
+			}
+		}
+		if p.peek().kind != "." {
+			return ps, nil
+		}
+		p.next()
+	}
+}
+
+//-- CONDITIONS --//
+
+type condition func(it item) (bool, error)
+
+type operand func(it item) (*dynamodb.AttributeValue, error)
+
+func parseCondition(s string, names map[string]*string, values map[string]*dynamodb.AttributeValue) (condition, error) {
+	p, err := newParser(s, names, values)
+	if err != nil {
+		return nil, err
+	}
+	c, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	return c, p.done()
+}
+
+func (p *parser) or() (condition, error) {
+	l, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("OR") {
+		p.next()
+		r, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		l = orCondition(l, r)
+	}
+	return l, nil
+}
+
+func orCondition(l, r condition) condition {
+	return func(it item) (bool, error) {
+		if ok, err := l(it); ok || err != nil {
+			return ok, err
+		}
+		return r(it)
+	}
+}
+
+func (p *parser) and() (condition, error) {
+	l, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("AND") {
+		p.next()
+		r, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		l = andCondition(l, r)
+	}
+	return l, nil
+}
+
+func andCondition(l, r condition) condition {
+	return func(it item) (bool, error) {
+		if ok, err := l(it); !ok || err != nil {
+			return ok, err
+		}
+		return r(it)
+	}
+}
+
+func (p *parser) not() (condition, error) {
+	if !p.isKeyword("NOT") {
+		return p.primary()
+	}
+	p.next()
+	c, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	return func(it item) (bool, error) {
+		ok, err := c(it)
+		return !ok, err
+	}, nil
+}
+
+func (p *parser) primary() (condition, error) {
+	if p.peek().kind == "(" {
+		p.next()
+		c, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		_, err = p.expect(")")
+		return c, err
+	}
+	if t := p.peek(); t.kind == "ident" && p.pos+1 < len(p.ts) && p.ts[p.pos+1].kind == "(" {
+		switch strings.ToLower(t.text) {
+		case "attribute_exists", "attribute_not_exists", "attribute_type", "begins_with", "contains":
+			return p.function()
+		}
+	}
+
+	l, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case p.isKeyword("BETWEEN"):
+		p.next()
+		lo, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		if !p.isKeyword("AND") {
+			return nil, validationError("expected AND in BETWEEN")
+		}
+		p.next()
+		hi, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		return func(it item) (bool, error) {
+			v, a, b, err := eval3(it, l, lo, hi)
+			if err != nil || v == nil || a == nil || b == nil {
+				return false, err
+			}
+			c1, ok1 := compare(a, v)
+			c2, ok2 := compare(v, b)
+			return ok1 && ok2 && c1 <= 0 && c2 <= 0, nil
+		}, nil
+	case p.isKeyword("IN"):
+		p.next()
+		if _, err := p.expect("("); err != nil {
+			return nil, err
+		}
+		var rs []operand
+		for {
+			r, err := p.operand()
+			if err != nil {
+				return nil, err
+			}
+			rs = append(rs, r)
+			if p.peek().kind != "," {
+				break
+			}
+			p.next()
+		}
+		if _, err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return func(it item) (bool, error) {
+			v, err := l(it)
+			if err != nil || v == nil {
+				return false, err
+			}
+			for _, r := range rs {
+				rv, err := r(it)
+				if err != nil {
+					return false, err
+				}
+				if rv != nil && equal(v, rv) {
+					return true, nil
+				}
+			}
+			return false, nil
+		}, nil
+	}
+
+	op := p.next().kind
+	r, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	var test func(c int) bool
+	switch op {
+	case "=":
+		return func(it item) (bool, error) {
+			a, b, _, err := eval3(it, l, r, nil)
+			return err == nil && a != nil && b != nil && equal(a, b), err
+		}, nil
+	case "<>":
+		return func(it item) (bool, error) {
+			a, b, _, err := eval3(it, l, r, nil)
+			return err == nil && !(a != nil && b != nil && equal(a, b)), err
+		}, nil
+	case "<":
+		test = func(c int) bool { return c < 0 }
+	case "<=":
+		test = func(c int) bool { return c <= 0 }
+	case ">":
+		test = func(c int) bool { return c > 0 }
+	case ">=":
+		test = func(c int) bool { return c >= 0 }
+	default:
+		return nil, validationError("expected a comparator but found %q", op)
+	}
+	return func(it item) (bool, error) {
+		a, b, _, err := eval3(it, l, r, nil)
+		if err != nil || a == nil || b == nil {
+			return false, err
+		}
+		c, ok := compare(a, b)
+		return ok && test(c), nil
+	}, nil
+}
+
+func (p *parser) function() (condition, error) {
+	fn := strings.ToLower(p.next().text)
+	p.next() // (
+	pa, err := p.path()
+	if err != nil {
+		return nil, err
+	}
+	var arg operand
+	if fn != "attribute_exists" && fn != "attribute_not_exists" {
+		if _, err := p.expect(","); err != nil {
+			return nil, err
+		}
+		if arg, err = p.operand(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	return func(it item) (bool, error) {
+		v := get(it, pa)
+		switch fn {
+		case "attribute_exists":
+			return v != nil, nil
+		case "attribute_not_exists":
+			return v == nil, nil
+		}
+		a, err := arg(it)
+		if err != nil || v == nil || a == nil {
+			return false, err
+		}
+		switch fn {
+		case "attribute_type":
+			return typeOf(v) == aws.StringValue(a.S), nil
+		case "begins_with":
+			switch {
+			case v.S != nil && a.S != nil:
+				return strings.HasPrefix(*v.S, *a.S), nil
+			case v.B != nil && a.B != nil:
+				return bytes.HasPrefix(v.B, a.B), nil
+			}
+			return false, nil
+		default: // contains
+			switch {
+			case v.S != nil && a.S != nil:
+				return strings.Contains(*v.S, *a.S), nil
+			case v.SS != nil && a.S != nil:
+				return containsString(v.SS, *a.S), nil
+			case v.NS != nil && a.N != nil:
+				return containsString(v.NS, *a.N), nil
+			case v.L != nil:
+				for _, e := range v.L {
+					if equal(e, a) {
+						return true, nil
+					}
+				}
+			}
+			return false, nil
+		}
+	}, nil
+}
+
+// a path, a value placeholder or size(path)
+func (p *parser) operand() (operand, error) {
+	t := p.peek()
+	if t.kind == "value" {
+		p.next()
+		v, ok := p.values[t.text]
+		if !ok {
+			return nil, validationError("undefined attribute value placeholder %s", t.text)
+		}
+		return func(item) (*dynamodb.AttributeValue, error) { return v, nil }, nil
+	}
+	if t.kind == "ident" && strings.EqualFold(t.text, "size") && p.pos+1 < len(p.ts) && p.ts[p.pos+1].kind == "(" {
+		p.next()
+		p.next()
+		pa, err := p.path()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return func(it item) (*dynamodb.AttributeValue, error) {
+			v := get(it, pa)
+			if v == nil {
+				return nil, nil
+			}
+			return &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(size(v)))}, nil
+		}, nil
+	}
+	pa, err := p.path()
+	if err != nil {
+		return nil, err
+	}
+	return func(it item) (*dynamodb.AttributeValue, error) { return get(it, pa), nil }, nil
+}
+
+// evaluates up to three operands, c may be nil
+func eval3(it item, a, b, c operand) (av, bv, cv *dynamodb.AttributeValue, err error) {
+	if av, err = a(it); err != nil {
+		return
+	}
+	if bv, err = b(it); err != nil {
+		return
+	}
+	if c != nil {
+		cv, err = c(it)
+	}
+	return
+}
+
+//-- UPDATES --//
+
+// an update applied to a copy of the stored item.  Values are computed
+// from the item as it was before the update, as dynamoDB does.
+type update struct {
+	actions []updateAction
+}
+
+type updateAction struct {
+	kind  string // SET, REMOVE, ADD or DELETE
+	path  path
+	value setValue
+}
+
+type setValue func(old item) (*dynamodb.AttributeValue, error)
+
+func parseUpdate(s string, names map[string]*string, values map[string]*dynamodb.AttributeValue) (*update, error) {
+	p, err := newParser(s, names, values)
+	if err != nil {
+		return nil, err
+	}
+	u := &update{}
+	for p.pos < len(p.ts) {
+		kw := strings.ToUpper(p.next().text)
+		switch kw {
+		case "SET", "REMOVE", "ADD", "DELETE":
+		default:
+			return nil, validationError("unexpected %q in update expression", kw)
+		}
+		for {
+			pa, err := p.path()
+			if err != nil {
+				return nil, err
+			}
+			a := updateAction{kind: kw, path: pa}
+			switch kw {
+			case "SET":
+				if _, err := p.expect("="); err != nil {
+					return nil, err
+				}
+				if a.value, err = p.setValue(); err != nil {
+					return nil, err
+				}
+			case "ADD", "DELETE":
+				o, err := p.operand()
+				if err != nil {
+					return nil, err
+				}
+				a.value = func(old item) (*dynamodb.AttributeValue, error) { return o(old) }
+			}
+			u.actions = append(u.actions, a)
+			if p.peek().kind != "," {
+				break
+			}
+			p.next()
+		}
+	}
+	return u, nil
+}
+
+func (p *parser) setValue() (setValue, error) {
+	l, err := p.setTerm()
+	if err != nil {
+		return nil, err
+	}
+	op := p.peek().kind
+	if op != "+" && op != "-" {
+		return l, nil
+	}
+	p.next()
+	r, err := p.setTerm()
+	if err != nil {
+		return nil, err
+	}
+	return func(old item) (*dynamodb.AttributeValue, error) {
+		a, err := l(old)
+		if err != nil {
+			return nil, err
+		}
+		b, err := r(old)
+		if err != nil {
+			return nil, err
+		}
+		if a == nil || b == nil || a.N == nil || b.N == nil {
+			return nil, validationError("an operand of %s is not a number", op)
+		}
+		if op == "-" {
+			return addNumbers(a, b, -1), nil
+		}
+		return addNumbers(a, b, 1), nil
+	}, nil
+}
+
+func (p *parser) setTerm() (setValue, error) {
+	t := p.peek()
+	if t.kind == "ident" && p.pos+1 < len(p.ts) && p.ts[p.pos+1].kind == "(" {
+		switch strings.ToLower(t.text) {
+		case "if_not_exists":
+			p.next()
+			p.next()
+			pa, err := p.path()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expect(","); err != nil {
+				return nil, err
+			}
+			d, err := p.setValue()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return func(old item) (*dynamodb.AttributeValue, error) {
+				if v := get(old, pa); v != nil {
+					return v, nil
+				}
+				return d(old)
+			}, nil
+		case "list_append":
+			p.next()
+			p.next()
+			a, err := p.setValue()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expect(","); err != nil {
+				return nil, err
+			}
+			b, err := p.setValue()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return func(old item) (*dynamodb.AttributeValue, error) {
+				av, err := a(old)
+				if err != nil {
+					return nil, err
+				}
+				bv, err := b(old)
+				if err != nil {
+					return nil, err
+				}
+				if av == nil || bv == nil || av.L == nil || bv.L == nil {
+					return nil, validationError("an operand of list_append is not a list")
+				}
+				return &dynamodb.AttributeValue{L: append(append([]*dynamodb.AttributeValue{}, av.L...), bv.L...)}, nil
+			}, nil
+		}
+	}
+	o, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	return func(old item) (*dynamodb.AttributeValue, error) {
+		v, err := o(old)
+		if err == nil && v == nil {
+			err = validationError("the attribute in an update expression does not exist")
+		}
+		return v, err
+	}, nil
+}
+
+// applies u to a copy of old and returns it along with the top level
+// attribute names it touched.
+func (u *update) apply(old item) (item, []string, error) {
+	vs := make([]*dynamodb.AttributeValue, len(u.actions))
+	for n, a := range u.actions {
+		if a.value == nil {
+			continue
+		}
+		v, err := a.value(old)
+		if err != nil {
+			return nil, nil, err
+		}
+		vs[n] = v
+	}
+	it := copyItem(old)
+	touched := make([]string, 0, len(u.actions))
+	for n, a := range u.actions {
+		touched = append(touched, a.path[0].(string))
+		switch a.kind {
+		case "SET":
+			if err := set(it, a.path, vs[n]); err != nil {
+				return nil, nil, err
+			}
+		case "REMOVE":
+			remove(it, a.path)
+		case "ADD":
+			cur := get(it, a.path)
+			switch {
+			case cur == nil:
+				if err := set(it, a.path, vs[n]); err != nil {
+					return nil, nil, err
+				}
+			case cur.N != nil && vs[n].N != nil:
+				if err := set(it, a.path, addNumbers(cur, vs[n], 1)); err != nil {
+					return nil, nil, err
+				}
+			default:
+				if err := set(it, a.path, setUnion(cur, vs[n])); err != nil {
+					return nil, nil, err
+				}
+			}
+		case "DELETE":
+			if cur := get(it, a.path); cur != nil {
+				if err := set(it, a.path, setDifference(cur, vs[n])); err != nil {
+					return nil, nil, err
+				}
+			}
+		}
+	}
+	return it, touched, nil
+}
+
+// parses a ProjectionExpression into the paths it selects
+func parseProjection(s string, names map[string]*string) ([]path, error) {
+	p, err := newParser(s, names, nil)
+	if err != nil {
+		return nil, err
+	}
+	var ps []path
+	for {
+		pa, err := p.path()
+		if err != nil {
+			return nil, err
+		}
+		ps = append(ps, pa)
+		if p.peek().kind != "," {
+			break
+		}
+		p.next()
+	}
+	return ps, p.done()
+}
+
+// the attributes of it selected by ps, nested paths select the whole top
+// level attribute
+func project(it item, ps []path) item {
+	if ps == nil {
+		return it
+	}
+	out := make(item)
+	for _, pa := range ps {
+		n := pa[0].(string)
+		if v, ok := it[n]; ok {
+			out[n] = v
+		}
+	}
+	return out
+}
+
+//-- VALUES --//
+
+func get(it item, pa path) *dynamodb.AttributeValue {
+	v, ok := it[pa[0].(string)]
+	if !ok {
+		return nil
+	}
+	for _, e := range pa[1:] {
+		switch k := e.(type) {
+		case string:
+			if v.M == nil {
+				return nil
+			}
+			if v, ok = v.M[k]; !ok {
+				return nil
+			}
+		case int:
+			if k >= len(v.L) {
+				return nil
+			}
+			v = v.L[k]
+		}
+	}
+	return v
+}
+
+func set(it item, pa path, v *dynamodb.AttributeValue) error {
+	if len(pa) == 1 {
+		it[pa[0].(string)] = v
+		return nil
+	}
+	parent := get(it, pa[:len(pa)-1])
+	if parent == nil {
+		return validationError("the document path of an update expression does not exist")
+	}
+	switch k := pa[len(pa)-1].(type) {
+	case string:
+		if parent.M == nil {
+			return validationError("the document path of an update expression is not a map")
+		}
+		parent.M[k] = v
+	case int:
+		if parent.L == nil {
+			return validationError("the document path of an update expression is not a list")
+		}
+		if k >= len(parent.L) {
+			parent.L = append(parent.L, v)
+		} else {
+			parent.L[k] = v
+		}
+	}
+	return nil
+}
+
+func remove(it item, pa path) {
+	if len(pa) == 1 {
+		delete(it, pa[0].(string))
+		return
+	}
+	parent := get(it, pa[:len(pa)-1])
+	if parent == nil {
+		return
+	}
+	switch k := pa[len(pa)-1].(type) {
+	case string:
+		delete(parent.M, k)
+	case int:
+		if k < len(parent.L) {
+			parent.L = append(parent.L[:k], parent.L[k+1:]...)
+		}
+	}
+}
+
+func typeOf(v *dynamodb.AttributeValue) string {
+	switch {
+	case v.S != nil:
+		return "S"
+	case v.N != nil:
+		return "N"
+	case v.B != nil:
+		return "B"
+	case v.BOOL != nil:
+		return "BOOL"
+	case v.NULL != nil:
+		return "NULL"
+	case v.SS != nil:
+		return "SS"
+	case v.NS != nil:
+		return "NS"
+	case v.BS != nil:
+		return "BS"
+	case v.L != nil:
+		return "L"
+	}
+	return "M"
+}
+
+func size(v *dynamodb.AttributeValue) int {
+	switch {
+	case v.S != nil:
+		return len(*v.S)
+	case v.B != nil:
+		return len(v.B)
+	case v.SS != nil:
+		return len(v.SS)
+	case v.NS != nil:
+		return len(v.NS)
+	case v.BS != nil:
+		return len(v.BS)
+	case v.L != nil:
+		return len(v.L)
+	}
+	return len(v.M)
+}
+
+// orders two scalars of the same type, ok is false if they cannot be
+// compared
+func compare(a, b *dynamodb.AttributeValue) (c int, ok bool) {
+	switch {
+	case a.S != nil && b.S != nil:
+		return strings.Compare(*a.S, *b.S), true
+	case a.N != nil && b.N != nil:
+		return number(a).Cmp(number(b)), true
+	case a.B != nil && b.B != nil:
+		return bytes.Compare(a.B, b.B), true
+	}
+	return 0, false
+}
+
+func equal(a, b *dynamodb.AttributeValue) bool {
+	if c, ok := compare(a, b); ok {
+		return c == 0
+	}
+	if typeOf(a) != typeOf(b) {
+		return false
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func number(v *dynamodb.AttributeValue) *big.Float {
+	f, _, _ := big.ParseFloat(*v.N, 10, 128, big.ToNearestEven)
+	if f == nil {
+		return new(big.Float)
+	}
+	return f
+}
+
+func addNumbers(a, b *dynamodb.AttributeValue, sign int) *dynamodb.AttributeValue {
+	y := number(b)
+	if sign < 0 {
+		y.Neg(y)
+	}
+	s := new(big.Float).Add(number(a), y).Text('g', -1)
+	return &dynamodb.AttributeValue{N: &s}
+}
+
+func setUnion(a, b *dynamodb.AttributeValue) *dynamodb.AttributeValue {
+	switch {
+	case a.SS != nil:
+		return &dynamodb.AttributeValue{SS: unionStrings(a.SS, b.SS)}
+	case a.NS != nil:
+		return &dynamodb.AttributeValue{NS: unionStrings(a.NS, b.NS)}
+	}
+	return a
+}
+
+func setDifference(a, b *dynamodb.AttributeValue) *dynamodb.AttributeValue {
+	diff := func(x, y []*string) []*string {
+		out := make([]*string, 0, len(x))
+		for _, s := range x {
+			if !containsString(y, *s) {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	switch {
+	case a.SS != nil:
+		return &dynamodb.AttributeValue{SS: diff(a.SS, b.SS)}
+	case a.NS != nil:
+		return &dynamodb.AttributeValue{NS: diff(a.NS, b.NS)}
+	}
+	return a
+}
+
+func unionStrings(a, b []*string) []*string {
+	out := append([]*string{}, a...)
+	for _, s := range b {
+		if !containsString(out, *s) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func containsString(ss []*string, s string) bool {
+	for _, e := range ss {
+		if *e == s {
+			return true
+		}
+	}
+	return false
+}
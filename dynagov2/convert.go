@@ -0,0 +1,171 @@
+// Copyright 2016 Appittome. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dynagov2
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	v1 "github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// This file converts between the types of the two sdks.  dynaGo builds
+// every request with v1 types, and the results are translated here
+// rather than encoding twice.
+
+//-- ATTRIBUTE VALUES --//
+
+func toItem(m map[string]*v1.AttributeValue) map[string]types.AttributeValue {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]types.AttributeValue, len(m))
+	for n, av := range m {
+		out[n] = toAttributeValue(av)
+	}
+	return out
+}
+
+func toAttributeValue(av *v1.AttributeValue) types.AttributeValue {
+	switch {
+	case av.S != nil:
+		return &types.AttributeValueMemberS{Value: *av.S}
+	case av.N != nil:
+		return &types.AttributeValueMemberN{Value: *av.N}
+	case av.B != nil:
+		return &types.AttributeValueMemberB{Value: av.B}
+	case av.SS != nil:
+		return &types.AttributeValueMemberSS{Value: aws.ToStringSlice(av.SS)}
+	case av.NS != nil:
+		return &types.AttributeValueMemberNS{Value: aws.ToStringSlice(av.NS)}
+	case av.BS != nil:
+		return &types.AttributeValueMemberBS{Value: av.BS}
+	case av.M != nil:
+		return &types.AttributeValueMemberM{Value: toItem(av.M)}
+	case av.L != nil:
+		l := make([]types.AttributeValue, len(av.L))
+		for i, e := range av.L {
+			l[i] = toAttributeValue(e)
+		}
+		return &types.AttributeValueMemberL{Value: l}
+	case av.BOOL != nil:
+		return &types.AttributeValueMemberBOOL{Value: *av.BOOL}
+	}
+	return &types.AttributeValueMemberNULL{Value: true}
+}
+
+func fromItem(m map[string]types.AttributeValue) map[string]*v1.AttributeValue {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]*v1.AttributeValue, len(m))
+	for n, av := range m {
+		out[n] = fromAttributeValue(av)
+	}
+	return out
+}
+
+func fromAttributeValue(av types.AttributeValue) *v1.AttributeValue {
+	switch v := av.(type) {
+	case *types.AttributeValueMemberS:
+		return &v1.AttributeValue{S: aws.String(v.Value)}
+	case *types.AttributeValueMemberN:
+		return &v1.AttributeValue{N: aws.String(v.Value)}
+	case *types.AttributeValueMemberB:
+		return &v1.AttributeValue{B: v.Value}
+	case *types.AttributeValueMemberSS:
+		return &v1.AttributeValue{SS: aws.StringSlice(v.Value)}
+	case *types.AttributeValueMemberNS:
+		return &v1.AttributeValue{NS: aws.StringSlice(v.Value)}
+	case *types.AttributeValueMemberBS:
+		return &v1.AttributeValue{BS: v.Value}
+	case *types.AttributeValueMemberM:
+		return &v1.AttributeValue{M: fromItem(v.Value)}
+	case *types.AttributeValueMemberL:
+		l := make([]*v1.AttributeValue, len(v.Value))
+		for i, e := range v.Value {
+			l[i] = fromAttributeValue(e)
+		}
+		return &v1.AttributeValue{L: l}
+	case *types.AttributeValueMemberBOOL:
+		return &v1.AttributeValue{BOOL: aws.Bool(v.Value)}
+	}
+	return &v1.AttributeValue{NULL: aws.Bool(true)}
+}
+
+func toNames(m map[string]*string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	return aws.ToStringMap(m)
+}
+
+//-- TABLES --//
+
+func toKeySchema(ks []*v1.KeySchemaElement) []types.KeySchemaElement {
+	out := make([]types.KeySchemaElement, len(ks))
+	for i, k := range ks {
+		out[i] = types.KeySchemaElement{
+			AttributeName: k.AttributeName,
+			KeyType:       types.KeyType(aws.ToString(k.KeyType)),
+		}
+	}
+	return out
+}
+
+func toProvisionedThroughput(pt *v1.ProvisionedThroughput) *types.ProvisionedThroughput {
+	if pt == nil {
+		return nil
+	}
+	return &types.ProvisionedThroughput{
+		ReadCapacityUnits:  pt.ReadCapacityUnits,
+		WriteCapacityUnits: pt.WriteCapacityUnits,
+	}
+}
+
+func toCreateTableInput(in *v1.CreateTableInput) *dynamodb.CreateTableInput {
+	out := &dynamodb.CreateTableInput{
+		TableName:                 in.TableName,
+		KeySchema:                 toKeySchema(in.KeySchema),
+		BillingMode:               types.BillingMode(aws.ToString(in.BillingMode)),
+		ProvisionedThroughput:     toProvisionedThroughput(in.ProvisionedThroughput),
+		TableClass:                types.TableClass(aws.ToString(in.TableClass)),
+		DeletionProtectionEnabled: in.DeletionProtectionEnabled,
+	}
+	for _, ad := range in.AttributeDefinitions {
+		out.AttributeDefinitions = append(out.AttributeDefinitions, types.AttributeDefinition{
+			AttributeName: ad.AttributeName,
+			AttributeType: types.ScalarAttributeType(aws.ToString(ad.AttributeType)),
+		})
+	}
+	for _, gsi := range in.GlobalSecondaryIndexes {
+		out.GlobalSecondaryIndexes = append(out.GlobalSecondaryIndexes, types.GlobalSecondaryIndex{
+			IndexName: gsi.IndexName,
+			KeySchema: toKeySchema(gsi.KeySchema),
+			Projection: &types.Projection{
+				ProjectionType:   types.ProjectionType(aws.ToString(gsi.Projection.ProjectionType)),
+				NonKeyAttributes: aws.ToStringSlice(gsi.Projection.NonKeyAttributes),
+			},
+			ProvisionedThroughput: toProvisionedThroughput(gsi.ProvisionedThroughput),
+		})
+	}
+	if ss := in.StreamSpecification; ss != nil {
+		out.StreamSpecification = &types.StreamSpecification{
+			StreamEnabled:  ss.StreamEnabled,
+			StreamViewType: types.StreamViewType(aws.ToString(ss.StreamViewType)),
+		}
+	}
+	if sse := in.SSESpecification; sse != nil {
+		out.SSESpecification = &types.SSESpecification{
+			Enabled:        sse.Enabled,
+			KMSMasterKeyId: sse.KMSMasterKeyId,
+			SSEType:        types.SSEType(aws.ToString(sse.SSEType)),
+		}
+	}
+	for _, t := range in.Tags {
+		out.Tags = append(out.Tags, types.Tag{Key: t.Key, Value: t.Value})
+	}
+	return out
+}
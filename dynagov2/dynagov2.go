@@ -0,0 +1,186 @@
+// Copyright 2016 Appittome. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package dynagov2 offers the dynaGo encoding for aws-sdk-go-v2.  The
+// same struct tags produce v2 request inputs and decode v2 items, so
+// services on either sdk can share their types.
+//
+//	out, err := client.PutItem(ctx, dynagov2.Marshal(usr))
+//
+// The encoding is that of dynaGo, which is built on aws-sdk-go v1: inputs
+// are encoded with the v1 types and then converted.  Importing dynagov2
+// therefore still pulls in aws-sdk-go v1, though no v1 client is ever
+// made.
+package dynagov2
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/japhyf/dynaGo"
+	"github.com/japhyf/dynaGo/internal/wait"
+)
+
+// Marshal is dynaGo.Marshal producing a v2 PutItemInput.
+func Marshal(i interface{}) *dynamodb.PutItemInput {
+	in := dynaGo.Marshal(i)
+	return &dynamodb.PutItemInput{
+		TableName:                 in.TableName,
+		Item:                      toItem(in.Item),
+		ConditionExpression:       in.ConditionExpression,
+		ExpressionAttributeNames:  toNames(in.ExpressionAttributeNames),
+		ExpressionAttributeValues: toItem(in.ExpressionAttributeValues),
+	}
+}
+
+// MarshalUpdate is dynaGo.MarshalUpdate producing a v2 UpdateItemInput.
+func MarshalUpdate(i interface{}) *dynamodb.UpdateItemInput {
+	in := dynaGo.MarshalUpdate(i)
+	return &dynamodb.UpdateItemInput{
+		TableName:                 in.TableName,
+		Key:                       toItem(in.Key),
+		UpdateExpression:          in.UpdateExpression,
+		ConditionExpression:       in.ConditionExpression,
+		ExpressionAttributeNames:  toNames(in.ExpressionAttributeNames),
+		ExpressionAttributeValues: toItem(in.ExpressionAttributeValues),
+	}
+}
+
+// Unmarshal is dynaGo.Unmarshal for a v2 item.
+func Unmarshal(m map[string]types.AttributeValue, i interface{}) error {
+	return dynaGo.Unmarshal(fromItem(m), i)
+}
+
+// Key is the primary key of an item in v2 types.
+type Key struct {
+	TableName  string
+	Attributes map[string]types.AttributeValue
+}
+
+type KeyMaker func(...interface{}) (Key, error)
+
+// CreateKeyMaker is dynaGo.CreateKeyMaker producing v2 keys.
+func CreateKeyMaker(rt reflect.Type) KeyMaker {
+	km := dynaGo.CreateKeyMaker(rt)
+	return func(kv ...interface{}) (Key, error) {
+		k, err := km(kv...)
+		if err != nil {
+			return Key{}, err
+		}
+		return Key{k.TableName(), toItem(k.Attributes())}, nil
+	}
+}
+
+func GetItemInput(km KeyMaker, kv ...interface{}) (*dynamodb.GetItemInput, error) {
+	k, err := km(kv...)
+	if err != nil {
+		return nil, err
+	}
+	return &dynamodb.GetItemInput{
+		TableName: aws.String(k.TableName),
+		Key:       k.Attributes,
+	}, nil
+}
+
+func DeleteItemInput(km KeyMaker, kv ...interface{}) (*dynamodb.DeleteItemInput, error) {
+	k, err := km(kv...)
+	if err != nil {
+		return nil, err
+	}
+	return &dynamodb.DeleteItemInput{
+		TableName: aws.String(k.TableName),
+		Key:       k.Attributes,
+	}, nil
+}
+
+//-- TABLES --//
+
+// TableAPI is the part of *dynamodb.Client CreateTable uses.
+type TableAPI interface {
+	CreateTable(context.Context, *dynamodb.CreateTableInput, ...func(*dynamodb.Options)) (*dynamodb.CreateTableOutput, error)
+	DescribeTable(context.Context, *dynamodb.DescribeTableInput, ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error)
+	UpdateTimeToLive(context.Context, *dynamodb.UpdateTimeToLiveInput, ...func(*dynamodb.Options)) (*dynamodb.UpdateTimeToLiveOutput, error)
+}
+
+// CreateTableInput is dynaGo.CreateTableInput producing a v2
+// CreateTableInput.
+func CreateTableInput(v interface{}, o dynaGo.TableOptions) *dynamodb.CreateTableInput {
+	return toCreateTableInput(dynaGo.CreateTableInput(v, o))
+}
+
+// CreateTable is dynaGo.CreateTableWithContext for a v2 client.  It
+// returns a dynaGo.TableExistsError if the table of v already exists,
+// and waits for the table and enables time to live when v has a field
+// tagged "ttl".
+func CreateTable(ctx context.Context, client TableAPI, v interface{}, o dynaGo.TableOptions) error {
+	params := CreateTableInput(v, o)
+	_, err := client.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: params.TableName})
+	if err == nil {
+		return dynaGo.TableExistsError{TableName: *params.TableName}
+	}
+	if !isNotFound(err) {
+		return err
+	}
+	if _, err := client.CreateTable(ctx, params); err != nil {
+		return err
+	}
+	ttl := dynaGo.TimeToLiveInput(v)
+	if o.Wait == nil && ttl == nil {
+		return nil
+	}
+	if err := WaitUntilTableActive(ctx, client, *params.TableName, o.Wait); err != nil {
+		return err
+	}
+	if ttl == nil {
+		return nil
+	}
+	_, err = client.UpdateTimeToLive(ctx, &dynamodb.UpdateTimeToLiveInput{
+		TableName: ttl.TableName,
+		TimeToLiveSpecification: &types.TimeToLiveSpecification{
+			AttributeName: ttl.TimeToLiveSpecification.AttributeName,
+			Enabled:       ttl.TimeToLiveSpecification.Enabled,
+		},
+	})
+	return err
+}
+
+// WaitUntilTableActive is dynaGo.WaitUntilTableActive for a v2 client.
+func WaitUntilTableActive(ctx context.Context, client TableAPI, tn string, o *dynaGo.WaitOptions) error {
+	var b wait.Backoff
+	if o != nil {
+		b = wait.Backoff(*o)
+	}
+	params := &dynamodb.DescribeTableInput{TableName: aws.String(tn)}
+	return wait.Poll(ctx, b, func(ctx context.Context) (bool, error) {
+		resp, err := client.DescribeTable(ctx, params)
+		switch {
+		case err == nil:
+			return tableActive(resp.Table), nil
+		case isNotFound(err):
+			return false, nil
+		}
+		return false, err
+	})
+}
+
+func tableActive(td *types.TableDescription) bool {
+	if td == nil || td.TableStatus != types.TableStatusActive {
+		return false
+	}
+	for _, gsi := range td.GlobalSecondaryIndexes {
+		if gsi.IndexStatus != types.IndexStatusActive || aws.ToBool(gsi.Backfilling) {
+			return false
+		}
+	}
+	return true
+}
+
+func isNotFound(err error) bool {
+	var nf *types.ResourceNotFoundException
+	return errors.As(err, &nf)
+}
@@ -0,0 +1,160 @@
+// Copyright 2016 Appittome. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dynagov2
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/japhyf/dynaGo"
+)
+
+type Profile struct {
+	Id      string `dynaGo:"ProfileId,HASH"`
+	Created int64  `dynaGo:",RANGE"`
+	Region  string `dynaGo:",gsi=ByRegion"`
+	Tags    []string
+	Avatar  []byte
+	Links   map[string]string
+	Version int64 `dynaGo:",version"`
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	p := Profile{
+		Id:      "p1",
+		Created: 1470000000,
+		Region:  "us-west",
+		Tags:    []string{"a", "b"},
+		Avatar:  []byte{0xca, 0xfe},
+		Links:   map[string]string{"home": "http://example.org"},
+	}
+	in := Marshal(p)
+	if *in.TableName != "Profiles" {
+		t.Errorf("unexpected table name: %s", *in.TableName)
+	}
+	if s, ok := in.Item["ProfileId"].(*types.AttributeValueMemberS); !ok || s.Value != "p1" {
+		t.Errorf("unexpected hash key attribute: %#v", in.Item["ProfileId"])
+	}
+	if in.ConditionExpression == nil || len(in.ExpressionAttributeNames) != 1 {
+		t.Errorf("expected the version condition: %v", in)
+	}
+
+	var out Profile
+	if err := Unmarshal(in.Item, &out); err != nil {
+		t.Fatal(err)
+	}
+	p.Version = 1
+	if !reflect.DeepEqual(p, out) {
+		t.Errorf("round trip mismatch:\n\t%#v\n\t%#v", p, out)
+	}
+}
+
+func TestKeyMaker(t *testing.T) {
+	km := CreateKeyMaker(reflect.TypeOf(Profile{}))
+	in, err := GetItemInput(km, "p1", 1470000000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *in.TableName != "Profiles" || len(in.Key) != 2 {
+		t.Errorf("unexpected key: %v", in)
+	}
+	if n, ok := in.Key["Created"].(*types.AttributeValueMemberN); !ok || n.Value != "1470000000" {
+		t.Errorf("unexpected range key attribute: %#v", in.Key["Created"])
+	}
+	if _, err := km("p1"); err == nil {
+		t.Error("expected an error for a missing range key")
+	}
+}
+
+func TestCreateTableInput(t *testing.T) {
+	in := CreateTableInput(Profile{}, dynaGo.TableOptions{ReadCapacity: 1, WriteCapacity: 2})
+	if len(in.KeySchema) != 2 || in.KeySchema[0].KeyType != types.KeyTypeHash {
+		t.Errorf("unexpected key schema: %v", in.KeySchema)
+	}
+	if len(in.GlobalSecondaryIndexes) != 1 || *in.GlobalSecondaryIndexes[0].IndexName != "ByRegion" {
+		t.Fatalf("unexpected indexes: %v", in.GlobalSecondaryIndexes)
+	}
+	if *in.GlobalSecondaryIndexes[0].ProvisionedThroughput.WriteCapacityUnits != 2 {
+		t.Errorf("expected the index to share the table throughput")
+	}
+	if len(in.AttributeDefinitions) != 3 {
+		t.Errorf("unexpected attribute definitions: %v", in.AttributeDefinitions)
+	}
+}
+
+type Lease struct {
+	Id      string    `dynaGo:"LeaseId,HASH"`
+	Expires time.Time `dynaGo:",ttl"`
+}
+
+// a TableAPI whose tables take a few DescribeTable calls to become ACTIVE
+type tableStub struct {
+	tables map[string]*types.TableDescription
+	polls  int
+	calls  []string
+}
+
+func (s *tableStub) CreateTable(ctx context.Context, in *dynamodb.CreateTableInput, _ ...func(*dynamodb.Options)) (*dynamodb.CreateTableOutput, error) {
+	s.calls = append(s.calls, "create")
+	td := &types.TableDescription{TableName: in.TableName, KeySchema: in.KeySchema, TableStatus: types.TableStatusCreating}
+	s.tables[*in.TableName] = td
+	return &dynamodb.CreateTableOutput{TableDescription: td}, nil
+}
+
+func (s *tableStub) DescribeTable(ctx context.Context, in *dynamodb.DescribeTableInput, _ ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+	td, ok := s.tables[*in.TableName]
+	if !ok {
+		s.calls = append(s.calls, "describe missing")
+		return nil, &types.ResourceNotFoundException{Message: aws.String("not found")}
+	}
+	if s.polls--; s.polls < 0 {
+		td.TableStatus = types.TableStatusActive
+	}
+	s.calls = append(s.calls, "describe "+string(td.TableStatus))
+	return &dynamodb.DescribeTableOutput{Table: td}, nil
+}
+
+func (s *tableStub) UpdateTimeToLive(ctx context.Context, in *dynamodb.UpdateTimeToLiveInput, _ ...func(*dynamodb.Options)) (*dynamodb.UpdateTimeToLiveOutput, error) {
+	s.calls = append(s.calls, "ttl "+*in.TimeToLiveSpecification.AttributeName)
+	return &dynamodb.UpdateTimeToLiveOutput{}, nil
+}
+
+func TestCreateTable(t *testing.T) {
+	s := &tableStub{tables: make(map[string]*types.TableDescription), polls: 2}
+	o := dynaGo.TableOptions{Wait: &dynaGo.WaitOptions{Delay: time.Millisecond}}
+	if err := CreateTable(context.Background(), s, Lease{}, o); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"describe missing", "create", "describe CREATING", "describe CREATING", "describe ACTIVE", "ttl Expires"}
+	if !reflect.DeepEqual(s.calls, want) {
+		t.Errorf("unexpected calls %v", s.calls)
+	}
+	if td := s.tables["Leases"]; td == nil || len(td.KeySchema) != 1 || *td.KeySchema[0].AttributeName != "LeaseId" {
+		t.Errorf("unexpected table %v", td)
+	}
+
+	s.calls = nil
+	err := CreateTable(context.Background(), s, Lease{}, dynaGo.TableOptions{})
+	if _, ok := err.(dynaGo.TableExistsError); !ok {
+		t.Errorf("expected a TableExistsError, found %v", err)
+	}
+	if len(s.calls) != 1 {
+		t.Errorf("expected only the table to be described, found %v", s.calls)
+	}
+
+	// without a ttl field nor WaitOptions, nothing is waited for
+	s.calls = nil
+	if err := CreateTable(context.Background(), s, Profile{}, dynaGo.TableOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(s.calls, []string{"describe missing", "create"}) {
+		t.Errorf("unexpected calls %v", s.calls)
+	}
+}
@@ -31,55 +31,42 @@ import (
 // the letter s.  For instance if there is a
 //   type Packet struct {...}
 // the associatedd dynamoDB table will be named "Packets" (for now?)
+// unless the type is registered to a shared table, see entity.go.
 //
 // Immsdiately this method only recognizes struct types that are
 // composed of exculsively int, string, and structs or slices and
 // pointers to any of those types. Any further unexpected type
 // will trigger a panic. Additional types shoould be trivial to add
 // following the given pattern.
+//
+// If the struct has a field tagged "version" the returned input is
+// conditioned on the stored version, see version.go.  Fields tagged
+// "createdAt" or "updatedAt" are stamped, see timestamps.go.
 func Marshal(i interface{}) *dynamodb.PutItemInput {
 	e := &valueEncoderState{make(map[string]*dynamodb.AttributeValue)}
 	encode(e, i)
 	tn := TableName(reflect.TypeOf(i))
-	return &dynamodb.PutItemInput{Item: e.item, TableName: &tn}
+	x := newExpression()
+	cs := writeConditions(x, reflect.Indirect(reflect.ValueOf(i)), e.item)
+	return &dynamodb.PutItemInput{
+		Item:                      e.item,
+		TableName:                 &tn,
+		ConditionExpression:       conditionExpression(cs),
+		ExpressionAttributeNames:  x.attributeNames(),
+		ExpressionAttributeValues: x.attributeValues(),
+	}
 }
 
 func TableName(t reflect.Type) string {
 	if t.Kind() == reflect.Ptr {
 		t = t.Elem()
 	}
+	if e, ok := registered(t); ok {
+		return e.table
+	}
 	return t.Name() + "s"
 }
 
-// Try to create a table if it doesn't already exist
-// If it does exist or cannot be created, return error
-//   - Tables are created from structs only, and will panic on any other type
-//   - Table name will be [structName] + s (ie type Doc struct {...} => table "Docs")
-func CreateTable(svc *dynamodb.DynamoDB, v interface{}, w int64, r int64) error {
-	tn := TableName(reflect.TypeOf(v))
-	if err := tableExists(svc, tn); err != nil {
-		return err
-	}
-	e := &tableEncoderState{
-		keySchema:            make([]*dynamodb.KeySchemaElement, 0),
-		attributeDefinitions: make([]*dynamodb.AttributeDefinition, 0),
-	}
-	encode(e, v)
-	params := &dynamodb.CreateTableInput{
-		TableName:            &tn,
-		KeySchema:            e.keySchema,
-		AttributeDefinitions: e.attributeDefinitions,
-		ProvisionedThroughput: &dynamodb.ProvisionedThroughput{
-			ReadCapacityUnits:  &r,
-			WriteCapacityUnits: &w,
-		},
-	}
-	if _, err := svc.CreateTable(params); err != nil {
-		return err
-	}
-	return nil
-}
-
 type encoderState interface{}
 type fieldTransform func(fs reflect.StructField, v reflect.Value) bool
 
@@ -114,6 +101,9 @@ func encode(e encoderState, i interface{}) {
 		ftr = func(fs reflect.StructField, fv reflect.Value) bool {
 			fn := getAttrName(fs)
 			valueEncoder(fs.Type)(es, fn, fv)
+			if p := getPrefix(fs); p != "" && es.item[fn] != nil {
+				es.item[fn] = addPrefix(p, fs.Type, es.item[fn])
+			}
 			return true
 		}
 	default:
@@ -127,24 +117,25 @@ func encode(e encoderState, i interface{}) {
 	if !foundPKey {
 		panic(&MissingKeyError{t, dynamodb.KeyTypeHash})
 	}
+	if es, ok := e.(*valueEncoderState); ok {
+		applyCompositions(v, es.item)
+		applyEntityType(t, es.item)
+	}
 }
 
-//-- UTIL --//
-// could be cached
-func tableExists(svc *dynamodb.DynamoDB, tn string) error {
-	params := &dynamodb.ListTablesInput{}
-	resp, err := svc.ListTables(params)
-	if err != nil {
-		return err
-	}
-	for _, name := range resp.TableNames {
-		if *name == tn {
-			return TableExistsError{tn}
-		}
-	}
-	return nil
+// applies the write-time field options of v to the encoded item and
+// returns the conditions the write has to satisfy.
+func writeConditions(x *expression, v reflect.Value, item map[string]*dynamodb.AttributeValue) []string {
+	getTTLField(v.Type()) // panics on a misused ttl tag
+	applyTimestamps(v, item)
+	cs := make([]string, 0)
+	if c := versionCondition(x, v, item); c != "" {
+		cs = append(cs, c)
+	}
+	return cs
 }
 
+//-- UTIL --//
 // The dynamoDB attribute name is determined by:
 // if the field tags contains a name use that name
 // if not, just use the native GoLang field name
@@ -177,3 +168,32 @@ func getKeyType(s reflect.StructField, v reflect.Value) (string, error) {
 	}
 	return "", &KeyTypeNotFoundError{v.Type()}
 }
+
+// the struct type of v, allowing one level of indirection
+func reflectType(v interface{}) reflect.Type {
+	t := reflect.TypeOf(v)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// returns the first top level field whose tag contains option o
+func getOptionField(t reflect.Type, o string) (reflect.StructField, bool) {
+	for n := 0; n < t.NumField(); n++ {
+		f := t.Field(n)
+		if _, opts := parseTag(f.Tag.Get("dynaGo")); opts.Contains(o) {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// the attribute names making up the primary key of t, partition first
+func keyAttrNames(t reflect.Type) []string {
+	ns := []string{getAttrName(t.Field(getPartitionKey(t)[0]))}
+	if rki, err := getRangeKey(t); err == nil {
+		ns = append(ns, getAttrName(t.Field(rki[0])))
+	}
+	return ns
+}
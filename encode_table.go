@@ -6,13 +6,79 @@ package dynaGo
 
 import (
 	"reflect"
+	"sort"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 )
 
+// Global secondary indexes are declared on the fields making up their
+// keys, naming the index with the "gsi" (partition key) and "gsirange"
+// (sort key) options.  A field may be part of several indexes:
+//   Origin string `dynaGo:",gsi=ByOrigin,gsi=ByOriginAlias"`
+//   Alias  string `dynaGo:",gsirange=ByOriginAlias"`
+// Indexes project ALL attributes.
+const (
+	tagGSI      = "gsi"
+	tagGSIRange = "gsirange"
+)
+
 type tableEncoderState struct {
 	keySchema            []*dynamodb.KeySchemaElement
 	attributeDefinitions []*dynamodb.AttributeDefinition
+	indexes              map[string][]*dynamodb.KeySchemaElement
+}
+
+func newTableEncoderState() *tableEncoderState {
+	return &tableEncoderState{
+		keySchema:            make([]*dynamodb.KeySchemaElement, 0),
+		attributeDefinitions: make([]*dynamodb.AttributeDefinition, 0),
+		indexes:              make(map[string][]*dynamodb.KeySchemaElement),
+	}
+}
+
+// adds an attribute definition unless one with the same name exists,
+// since attributes may be both table and index keys.
+func (e *tableEncoderState) define(an, st string) {
+	for _, ad := range e.attributeDefinitions {
+		if *ad.AttributeName == an {
+			return
+		}
+	}
+	e.attributeDefinitions = append(e.attributeDefinitions,
+		&dynamodb.AttributeDefinition{
+			AttributeName: &an,
+			AttributeType: &st,
+		})
+}
+
+// the global secondary indexes found while encoding, in name order.
+// Panics if an index has no partition key.
+func (e *tableEncoderState) globalSecondaryIndexes(t reflect.Type) []*dynamodb.GlobalSecondaryIndex {
+	ns := make([]string, 0, len(e.indexes))
+	for n := range e.indexes {
+		ns = append(ns, n)
+	}
+	sort.Strings(ns)
+	gsis := make([]*dynamodb.GlobalSecondaryIndex, 0, len(ns))
+	for _, n := range ns {
+		ks := e.indexes[n]
+		// dynamoDB expects the HASH key first
+		sort.SliceStable(ks, func(i, j int) bool {
+			return *ks[i].KeyType == dynamodb.KeyTypeHash && *ks[j].KeyType != dynamodb.KeyTypeHash
+		})
+		if *ks[0].KeyType != dynamodb.KeyTypeHash {
+			panic(&MissingKeyError{t, dynamodb.KeyTypeHash + " (index " + n + ")"})
+		}
+		gsis = append(gsis, &dynamodb.GlobalSecondaryIndex{
+			IndexName: aws.String(n),
+			KeySchema: ks,
+			Projection: &dynamodb.Projection{
+				ProjectionType: aws.String(dynamodb.ProjectionTypeAll),
+			},
+		})
+	}
+	return gsis
 }
 
 func (e *tableEncoderState) Error(err error) {
@@ -20,6 +86,9 @@ func (e *tableEncoderState) Error(err error) {
 }
 
 func tableEncoder(t reflect.Type) tableEncoderFunc {
+	if t == timeType {
+		return intTableEncoder
+	}
 	switch t.Kind() {
 	case reflect.Slice, reflect.Map:
 		return notAllowedTableEncoder
@@ -77,6 +146,21 @@ func tableUnsupportedTypeEncoder(e *tableEncoderState, s reflect.StructField, v
 
 func attributeEncoder(e *tableEncoderState, s reflect.StructField, v reflect.Value, st string) string {
 	an := getAttrName(s)
+	if getPrefix(s) != "" {
+		st = dynamodb.ScalarAttributeTypeS
+	}
+	_, opts := parseTag(s.Tag.Get("dynaGo"))
+	for _, ik := range [][2]string{{tagGSI, dynamodb.KeyTypeHash}, {tagGSIRange, dynamodb.KeyTypeRange}} {
+		o, kt := ik[0], ik[1]
+		for _, in := range opts.Values(o) {
+			e.indexes[in] = append(e.indexes[in],
+				&dynamodb.KeySchemaElement{
+					AttributeName: aws.String(an),
+					KeyType:       aws.String(kt),
+				})
+			e.define(an, st)
+		}
+	}
 	kt, err := getKeyType(s, v)
 	//if this is not a key attribute, the table schema doesn't care
 	if err != nil {
@@ -87,10 +171,6 @@ func attributeEncoder(e *tableEncoderState, s reflect.StructField, v reflect.Val
 			AttributeName: &an,
 			KeyType:       &kt,
 		})
-	e.attributeDefinitions = append(e.attributeDefinitions,
-		&dynamodb.AttributeDefinition{
-			AttributeName: &an,
-			AttributeType: &st,
-		})
+	e.define(an, st)
 	return kt
 }
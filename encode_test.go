@@ -8,6 +8,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"os"
 	"reflect"
 	"testing"
 	"time"
@@ -16,6 +17,8 @@ import (
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/japhyf/dynaGo/dynagotest"
 )
 
 /*
@@ -117,14 +120,24 @@ var TablesSchema = map[string]*dynamodb.CreateTableInput{
 	},
 }
 */
-var cred = &credentials.SharedCredentialsProvider{Profile: "admin_marcus"}
-var svc = dynamodb.New(
-	session.New(),
-	&aws.Config{
-		Credentials: credentials.NewCredentials(cred),
-		Endpoint:    aws.String("http://localhost:8000"),
-		Region:      aws.String("us-east-1"),
-	})
+// the tests run against the in-memory fake unless DYNAGO_TEST_ENDPOINT
+// names a dynamoDB, eg. http://localhost:8000 for DynamoDB Local.
+var svc = testService()
+
+func testService() dynamodbiface.DynamoDBAPI {
+	ep := os.Getenv("DYNAGO_TEST_ENDPOINT")
+	if ep == "" {
+		return dynagotest.New()
+	}
+	cred := &credentials.SharedCredentialsProvider{Profile: "admin_marcus"}
+	return dynamodb.New(
+		session.New(),
+		&aws.Config{
+			Credentials: credentials.NewCredentials(cred),
+			Endpoint:    aws.String(ep),
+			Region:      aws.String("us-east-1"),
+		})
+}
 
 func TestEncodeTables(t *testing.T) {
 	t.Log(`create table 'Tags'`)
@@ -238,7 +251,7 @@ func TestGetBatchItem(t *testing.T) {
 		t.Errorf("could not create key Usr{\"UserId\":\"2000\"}")
 	}
 	if err := AppendToBatchGet(bi, tag_km, "talkietalk", 1234); err != nil {
-		t.Errorf("could not create key tag{\"Name\":\"talkietalk\"}:: " + err.Error())
+		t.Errorf("could not create key tag{\"Name\":\"talkietalk\"}:: %s", err)
 	}
 	//do get
 	resp, err := svc.BatchGetItem(bi)
@@ -270,7 +283,7 @@ func TestGetValues(t *testing.T) {
 	t.Log("Get usr1...")
 	tryGetValue(t, Usr{}, usr1, "2000")
 	t.Log("Get ses..")
-	tryGetValue(t, Session{}, ses0, "abc")
+	tryGetValue(t, Session{}, ses0, "1000", "abc")
 }
 
 func tryGetValue(t *testing.T, i interface{}, v interface{}, k ...interface{}) {
@@ -0,0 +1,97 @@
+// Copyright 2016 Appittome. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dynaGo
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// MarshalUpdate returns a dynamodb.UpdateItemInput representitive of i.
+// Where Marshal replaces the whole item, the update SETs only the
+// attributes Marshal would have written, leaving any other attributes
+// of the stored item alone.  The key is taken from the HASH and RANGE
+// fields of i, exactly as Marshal encodes them.
+//
+// The same write-time options as Marshal apply (eg. "version"), except
+// that a "createdAt" attribute is only written if the stored item has
+// none, and that a "sparse" composed attribute Marshal leaves out is
+// REMOVEd.
+func MarshalUpdate(i interface{}) *dynamodb.UpdateItemInput {
+	in, _ := marshalUpdate(i)
+	return in
+}
+
+// MarshalUpdate, also returning the attributes the update sets
+func marshalUpdate(i interface{}) (*dynamodb.UpdateItemInput, map[string]*dynamodb.AttributeValue) {
+	e := &valueEncoderState{make(map[string]*dynamodb.AttributeValue)}
+	encode(e, i)
+	v := reflect.Indirect(reflect.ValueOf(i))
+	tn := TableName(v.Type())
+	x := newExpression()
+	cs := writeConditions(x, v, e.item)
+
+	k := make(map[string]*dynamodb.AttributeValue)
+	for _, n := range keyAttrNames(v.Type()) {
+		k[n] = e.item[n]
+		delete(e.item, n)
+	}
+	return &dynamodb.UpdateItemInput{
+		TableName:                 &tn,
+		Key:                       k,
+		UpdateExpression:          updateExpression(x, e.item, createOnlyAttrNames(v.Type()), sparseRemovals(v)),
+		ConditionExpression:       conditionExpression(cs),
+		ExpressionAttributeNames:  x.attributeNames(),
+		ExpressionAttributeValues: x.attributeValues(),
+	}, e.item
+}
+
+// builds "SET #n0 = :v0, ..." from item, followed by "REMOVE #n1, ..."
+// for the attributes named in remove.  Attributes are visited in name
+// order so the expression is stable between calls.  Attributes named in
+// onCreate are wrapped in if_not_exists.
+func updateExpression(x *expression, item map[string]*dynamodb.AttributeValue, onCreate, remove []string) *string {
+	if len(item) == 0 && len(remove) == 0 {
+		return nil
+	}
+	ns := make([]string, 0, len(item))
+	for n := range item {
+		ns = append(ns, n)
+	}
+	sort.Strings(ns)
+	sets := make([]string, 0, len(ns))
+	for _, n := range ns {
+		p, vp := x.name(n), x.value(item[n])
+		if containsString(onCreate, n) {
+			vp = "if_not_exists(" + p + ", " + vp + ")"
+		}
+		sets = append(sets, p+" = "+vp)
+	}
+	var clauses []string
+	if len(sets) > 0 {
+		clauses = append(clauses, "SET "+strings.Join(sets, ", "))
+	}
+	if len(remove) > 0 {
+		rs := make([]string, 0, len(remove))
+		for _, n := range remove {
+			rs = append(rs, x.name(n))
+		}
+		clauses = append(clauses, "REMOVE "+strings.Join(rs, ", "))
+	}
+	s := strings.Join(clauses, " ")
+	return &s
+}
+
+func containsString(ss []string, s string) bool {
+	for _, e := range ss {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
@@ -9,13 +9,19 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 )
 
+var timeType = reflect.TypeOf(time.Time{})
+
 type valueEncoderFunc func(e *valueEncoderState, n string, v reflect.Value) string
 
 func valueEncoder(t reflect.Type) valueEncoderFunc {
+	if t == timeType {
+		return timeValueEncoder
+	}
 	switch t.Kind() {
 	case reflect.Slice:
 		return sliceValueEncoder
@@ -61,6 +67,16 @@ func stringValueEncoder(e *valueEncoderState, n string, v reflect.Value) string
 	}
 	return str
 }
+// time.Time is stored as a unix timestamp (seconds), the zero time is
+// treated like an empty string and omitted.
+func timeValueEncoder(e *valueEncoderState, n string, v reflect.Value) string {
+	tm := v.Interface().(time.Time)
+	str := strconv.FormatInt(tm.Unix(), 10)
+	if !tm.IsZero() && e != nil {
+		e.item[n] = &dynamodb.AttributeValue{N: &str}
+	}
+	return str
+}
 func structValueEncoder(e *valueEncoderState, n string, v reflect.Value) string {
 	i := getPartitionKey(v.Type())
 	str := v.FieldByIndex(i).String()
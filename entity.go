@@ -0,0 +1,152 @@
+// Copyright 2016 Appittome. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dynaGo
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// Several entity types may share one table.  Registering a struct type
+// with the table stores its items there, TableName returns the shared
+// table, and Marshal writes the entity type name to TypeAttribute so
+// that mixed results can be decoded with UnmarshalEntity:
+//   dynaGo.Register("App", Usr{})
+//   dynaGo.Register("App", Session{})
+//   it := dynaGo.NewQueryIterator(ctx, svc, qi, nil)
+//   for it.Next() {
+//       switch v := it.Item().(type) {
+//       case *Usr: ...
+//       case *Session: ...
+//       }
+//   }
+// The types of a table must agree on its key attributes.  The schema of
+// the table, as created, compared and migrated, is the union of the
+// attributes and indexes of all of them.
+
+// TypeAttribute is the attribute holding the entity type name of the
+// items of registered types.
+var TypeAttribute = "_type"
+
+type entity struct {
+	table string
+	name  string
+}
+
+var registry = struct {
+	sync.RWMutex
+	types map[reflect.Type]entity
+	names map[string]reflect.Type
+}{types: make(map[reflect.Type]entity), names: make(map[string]reflect.Type)}
+
+// Register stores the items of the struct type of v in table, under the
+// name of the type.  It panics if the type or its name are already
+// registered otherwise.
+func Register(table string, v interface{}) {
+	RegisterName(table, reflectType(v).Name(), v)
+}
+
+// RegisterName is Register storing the items of v under the entity type
+// name.  Names are unique across tables.
+func RegisterName(table, name string, v interface{}) {
+	t := reflectType(v)
+	if t.Kind() != reflect.Struct {
+		panic(&OnlyStructsSupportedError{t.Kind()})
+	}
+	e := entity{table, name}
+	registry.Lock()
+	defer registry.Unlock()
+	if r, ok := registry.types[t]; ok && r != e {
+		panic(&EntityRegistrationError{name, t})
+	}
+	if r, ok := registry.names[name]; ok && r != t {
+		panic(&EntityRegistrationError{name, t})
+	}
+	registry.types[t] = e
+	registry.names[name] = t
+}
+
+// the registration of t, false if t is not registered
+func registered(t reflect.Type) (entity, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	e, ok := registry.types[t]
+	return e, ok
+}
+
+// t and the other types registered to its table, by entity type name
+func tableTypes(t reflect.Type) []reflect.Type {
+	e, ok := registered(t)
+	if !ok {
+		return []reflect.Type{t}
+	}
+	registry.RLock()
+	defer registry.RUnlock()
+	ns := make([]string, 0)
+	for _, r := range registry.types {
+		if r.table == e.table {
+			ns = append(ns, r.name)
+		}
+	}
+	sort.Strings(ns)
+	ts := make([]reflect.Type, len(ns))
+	for i, n := range ns {
+		ts[i] = registry.names[n]
+	}
+	return ts
+}
+
+// the registered type named by the TypeAttribute of item
+func entityType(item map[string]*dynamodb.AttributeValue) (reflect.Type, error) {
+	av, ok := item[TypeAttribute]
+	if !ok || av.S == nil {
+		return nil, &UnknownEntityError{}
+	}
+	registry.RLock()
+	defer registry.RUnlock()
+	t, ok := registry.names[*av.S]
+	if !ok {
+		return nil, &UnknownEntityError{*av.S}
+	}
+	return t, nil
+}
+
+// writes the entity type name of t to item, if t is registered
+func applyEntityType(t reflect.Type, item map[string]*dynamodb.AttributeValue) {
+	if e, ok := registered(t); ok {
+		name := e.name
+		item[TypeAttribute] = &dynamodb.AttributeValue{S: &name}
+	}
+}
+
+// UnmarshalEntity decodes item into a new value of the registered type
+// named by its TypeAttribute, and returns a pointer to it.  Items
+// without a registered type name are reported with an
+// *UnknownEntityError.
+func UnmarshalEntity(item map[string]*dynamodb.AttributeValue) (interface{}, error) {
+	t, err := entityType(item)
+	if err != nil {
+		return nil, err
+	}
+	o := reflect.New(t).Interface()
+	if err := Unmarshal(item, o); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// whether item may be decoded into t: it is of t, or either is not a
+// registered entity
+func isEntityOf(t reflect.Type, item map[string]*dynamodb.AttributeValue) bool {
+	e, ok := registered(t)
+	if !ok {
+		return true
+	}
+	av, ok := item[TypeAttribute]
+	return !ok || av.S == nil || *av.S == e.name
+}
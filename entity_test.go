@@ -0,0 +1,183 @@
+// Copyright 2016 Appittome. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dynaGo
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/japhyf/dynaGo/dynagotest"
+)
+
+type Customer struct {
+	PK   string `dynaGo:",HASH"`
+	SK   string `dynaGo:",RANGE"`
+	Name string
+}
+
+type Order struct {
+	PK    string `dynaGo:",HASH"`
+	SK    string `dynaGo:",RANGE"`
+	Total int64
+}
+
+func init() {
+	Register("Shop", Customer{})
+	RegisterName("Shop", "ORDER", &Order{})
+}
+
+func TestEntities(t *testing.T) {
+	if tn := TableName(reflect.TypeOf(&Order{})); tn != "Shop" {
+		t.Errorf("expected the shared table, found %s", tn)
+	}
+	in := Marshal(Order{PK: "c1", SK: "o1", Total: 5})
+	if *in.Item[TypeAttribute].S != "ORDER" {
+		t.Errorf("unexpected entity type: %v", in.Item[TypeAttribute])
+	}
+
+	db := dynagotest.New()
+	if err := CreateTable(db, Customer{}, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []interface{}{Customer{"c1", "c1", "Ann"}, Order{"c1", "o1", 5}, Order{"c1", "o2", 7}} {
+		if err := Put(db, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	qi, _ := QueryOnPartition(CreateKeyMaker(reflect.TypeOf(Customer{})), "c1")
+	var found []string
+	it := NewQueryIterator(aws.BackgroundContext(), db, qi, nil)
+	for it.Next() {
+		switch v := it.Item().(type) {
+		case *Customer:
+			found = append(found, "customer "+v.Name)
+		case *Order:
+			found = append(found, "order "+v.SK)
+		}
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(found, []string{"customer Ann", "order o1", "order o2"}) {
+		t.Errorf("unexpected items: %v", found)
+	}
+
+	b := &dynamodb.BatchGetItemInput{}
+	AppendToBatchGet(b, CreateKeyMaker(reflect.TypeOf(Customer{})), "c1", "c1")
+	AppendToBatchGet(b, CreateKeyMaker(reflect.TypeOf(Order{})), "c1", "o2")
+	var cs []Customer
+	var os []*Order
+	if err := BatchGet(db, b, &cs, &os); err != nil {
+		t.Fatal(err)
+	}
+	if len(cs) != 1 || len(os) != 1 || os[0].Total != 7 {
+		t.Errorf("unexpected batch results: %v %v", cs, os)
+	}
+
+	if _, err := UnmarshalEntity(map[string]*dynamodb.AttributeValue{"PK": {S: aws.String("x")}}); err == nil {
+		t.Error("expected an error for an item without entity type")
+	}
+}
+
+type Person struct {
+	PK   string `dynaGo:",HASH"`
+	SK   string `dynaGo:",RANGE"`
+	Mail string `dynaGo:",gsi=ByMail"`
+}
+
+type Pet struct {
+	PK   string `dynaGo:",HASH"`
+	SK   string `dynaGo:",RANGE"`
+	Name string `dynaGo:",gsi=ByName"`
+	Age  int64  `dynaGo:",gsirange=ByName"`
+}
+
+func init() {
+	Register("Home", Person{})
+	Register("Home", Pet{})
+}
+
+func TestSharedTableSchema(t *testing.T) {
+	in := CreateTableInput(Pet{}, TableOptions{})
+	var gsis []string
+	for _, gsi := range in.GlobalSecondaryIndexes {
+		gsis = append(gsis, *gsi.IndexName)
+	}
+	if !reflect.DeepEqual(gsis, []string{"ByMail", "ByName"}) {
+		t.Errorf("expected the indexes of both types, found %v", gsis)
+	}
+	ads := attributeDefinitionMap(in.AttributeDefinitions)
+	if len(ads) != 5 || ads["Mail"] != "S" || ads["Age"] != "N" {
+		t.Errorf("unexpected attribute definitions: %v", ads)
+	}
+
+	db := dynagotest.New()
+	if err := CreateTable(db, Person{}, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []interface{}{Person{}, Pet{}} {
+		if ds, err := DiffTable(db, v); err != nil || len(ds) != 0 {
+			t.Errorf("expected no drift for %T, found %v, %v", v, ds, err)
+		}
+		if err := EnsureTable(db, v, TableOptions{}); err != nil {
+			t.Errorf("unexpected error for %T: %v", v, err)
+		}
+	}
+	steps, err := PlanMigration(db, Person{}, Pet{})
+	if err != nil || len(steps) != 0 {
+		t.Errorf("expected no migration, found %v, %v", steps, err)
+	}
+}
+
+func TestTypedIteratorsOnSharedTable(t *testing.T) {
+	db := dynagotest.New()
+	if err := CreateTable(db, Customer{}, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []interface{}{Customer{"c1", "c1", "Ann"}, Order{"c1", "o1", 5}, Order{"c1", "o2", 7}, Customer{"c2", "c2", "Bo"}} {
+		if err := Put(db, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	qi, _ := QueryOnPartition(CreateKeyMaker(reflect.TypeOf(Customer{})), "c1")
+	it := NewQueryIterator(aws.BackgroundContext(), db, qi, Customer{})
+	var found []string
+	for it.Next() {
+		found = append(found, it.Item().(*Customer).Name)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(found, []string{"Ann"}) {
+		t.Errorf("expected the customer only, found %v", found)
+	}
+
+	it = NewScanIterator(aws.BackgroundContext(), db, &dynamodb.ScanInput{TableName: aws.String("Shop")}, Order{})
+	var total int64
+	for it.Next() {
+		total += it.Item().(*Order).Total
+	}
+	if err := it.Err(); err != nil || total != 12 {
+		t.Errorf("expected the orders only, found a total of %d, %v", total, err)
+	}
+
+	var mu sync.Mutex
+	found = nil
+	err := ParallelScan(aws.BackgroundContext(), db, Customer{}, 3, func(v interface{}) error {
+		mu.Lock()
+		defer mu.Unlock()
+		found = append(found, v.(*Customer).Name)
+		return nil
+	})
+	sort.Strings(found)
+	if err != nil || !reflect.DeepEqual(found, []string{"Ann", "Bo"}) {
+		t.Errorf("expected the customers only, found %v, %v", found, err)
+	}
+}
@@ -2,6 +2,11 @@ package dynaGo
 
 import (
 	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
 )
 
 type TableExistsError struct {
@@ -78,6 +83,12 @@ func (e *KeyValueOfIncorrectType) Error() string {
 	return "dynaGo: Expected key type: " + e.expect.String() + " found:" + e.found.String()
 }
 
+// UnsupportedKeyKindError was the panic of a KeyMaker for a key field
+// of an unsupported kind.
+//
+// Deprecated: such key fields are refused when the KeyMaker is created,
+// and key values of the wrong kind are reported as a
+// *KeyValueOfIncorrectType.
 type UnsupportedKeyKindError struct {
 	Kind reflect.Kind
 }
@@ -85,3 +96,237 @@ type UnsupportedKeyKindError struct {
 func (e *UnsupportedKeyKindError) Error() string {
 	return "dynaGo: partitionkey has unsupported kind - " + e.Kind.String()
 }
+
+type TagOptionKindError struct {
+	Option string
+	Kind   reflect.Kind
+}
+
+func (e *TagOptionKindError) Error() string {
+	return "dynaGo: tag option " + e.Option + " cannot be used on kind " + e.Kind.String()
+}
+
+// ComposedValueError is returned by Unmarshal when a composed attribute
+// does not match the components declared by its "compose" option.  It
+// is also the panic of Marshal, and returned by a KeyMaker, when a
+// component other than the last contains "#".
+type ComposedValueError struct {
+	AttributeName string
+	Value         string
+}
+
+func (e *ComposedValueError) Error() string {
+	return "dynaGo: " + e.AttributeName + " value " + strconv.Quote(e.Value) + " does not match its composition"
+}
+
+// EntityRegistrationError is the panic of Register when a type or an
+// entity type name is registered twice.
+type EntityRegistrationError struct {
+	Name string
+	Type reflect.Type
+}
+
+func (e *EntityRegistrationError) Error() string {
+	return "dynaGo: cannot register " + e.Type.String() + " as " + e.Name + ", the type or name is already registered"
+}
+
+// UnknownEntityError is returned when an item has no TypeAttribute, or
+// names a type that is not registered.
+type UnknownEntityError struct {
+	Name string
+}
+
+func (e *UnknownEntityError) Error() string {
+	if e.Name == "" {
+		return "dynaGo: item has no entity type"
+	}
+	return "dynaGo: unknown entity type " + e.Name
+}
+
+// ItemNotFoundError is returned by Get when the table holds no item with
+// the key of the value given.
+type ItemNotFoundError struct {
+	TableName string
+	Key       map[string]*dynamodb.AttributeValue
+}
+
+func (e *ItemNotFoundError) Error() string {
+	return "dynaGo: no item " + keyString(e.Key) + " in " + e.TableName
+}
+
+// UnknownIndexError is returned when a type declares no global secondary
+// index of the name given.
+type UnknownIndexError struct {
+	Type  reflect.Type
+	Index string
+}
+
+func (e *UnknownIndexError) Error() string {
+	return "dynaGo: " + e.Type.String() + " declares no index " + e.Index
+}
+
+// VersionConflictError is returned by Put and Update when the stored
+// item no longer holds the version the write expected, meaning it was
+// modified (or created) by someone else in the meantime.
+type VersionConflictError struct {
+	TableName string
+	Version   int64
+	Err       error
+}
+
+func (e *VersionConflictError) Error() string {
+	return "dynaGo: version conflict in " + e.TableName + ", expected version " + strconv.FormatInt(e.Version, 10)
+}
+
+// Unwrap returns the failed condition reported by dynamoDB.
+func (e *VersionConflictError) Unwrap() error {
+	return e.Err
+}
+
+// SchemaDriftError is returned by EnsureTable when an existing table
+// does not match the schema derived from its struct.
+type SchemaDriftError struct {
+	TableName   string
+	Differences []SchemaDifference
+}
+
+func (e *SchemaDriftError) Error() string {
+	ds := make([]string, 0, len(e.Differences))
+	for _, d := range e.Differences {
+		ds = append(ds, d.String())
+	}
+	return "dynaGo: table " + e.TableName + " differs from its struct: " + strings.Join(ds, "; ")
+}
+
+type InvalidCursorError struct {
+	Reason string
+}
+
+func (e *InvalidCursorError) Error() string {
+	return "dynaGo: invalid cursor: " + e.Reason
+}
+
+// ParallelScanError holds the error that stopped each failed segment
+// of a ParallelScan.
+type ParallelScanError struct {
+	Errors map[int]error
+}
+
+func (e *ParallelScanError) Error() string {
+	segs := make([]int, 0, len(e.Errors))
+	for seg := range e.Errors {
+		segs = append(segs, seg)
+	}
+	sort.Ints(segs)
+	es := make([]string, 0, len(segs))
+	for _, seg := range segs {
+		es = append(es, "segment "+strconv.Itoa(seg)+": "+e.Errors[seg].Error())
+	}
+	return "dynaGo: parallel scan failed: " + strings.Join(es, "; ")
+}
+
+type BatchConditionError struct {
+	TableName string
+}
+
+func (e *BatchConditionError) Error() string {
+	return "dynaGo: items of " + e.TableName + " are written conditionally and cannot be batched"
+}
+
+// BatchWriteError reports the write requests that were still
+// unprocessed when BatchWrite gave up, by table.  Err is set if a
+// BatchWriteItem call failed outright.
+type BatchWriteError struct {
+	Unprocessed map[string][]*dynamodb.WriteRequest
+	Err         error
+}
+
+func (e *BatchWriteError) Error() string {
+	n := 0
+	for _, wrs := range e.Unprocessed {
+		n += len(wrs)
+	}
+	s := "dynaGo: batch write left " + strconv.Itoa(n) + " items unprocessed"
+	if e.Err != nil {
+		s += ": " + e.Err.Error()
+	}
+	return s
+}
+
+// Unwrap returns the error of the failed BatchWriteItem call, if any.
+func (e *BatchWriteError) Unwrap() error {
+	return e.Err
+}
+
+// BatchGetError reports the keys that were still unprocessed when
+// BatchGet gave up, by table.  Err is set if a BatchGetItem call failed
+// outright.
+type BatchGetError struct {
+	Unprocessed map[string]*dynamodb.KeysAndAttributes
+	Err         error
+}
+
+func (e *BatchGetError) Error() string {
+	n := 0
+	for _, ka := range e.Unprocessed {
+		n += len(ka.Keys)
+	}
+	s := "dynaGo: batch get left " + strconv.Itoa(n) + " keys unprocessed"
+	if e.Err != nil {
+		s += ": " + e.Err.Error()
+	}
+	return s
+}
+
+// Unwrap returns the error of the failed BatchGetItem call, if any.
+func (e *BatchGetError) Unwrap() error {
+	return e.Err
+}
+
+type TxTooLargeError struct {
+	Limit int
+}
+
+func (e *TxTooLargeError) Error() string {
+	return "dynaGo: transactions are limited to " + strconv.Itoa(e.Limit) + " operations"
+}
+
+type TxDuplicateItemError struct {
+	Op TxOp
+}
+
+func (e *TxDuplicateItemError) Error() string {
+	return "dynaGo: transaction touches an item more than once: " + e.Op.String()
+}
+
+// TxCanceledError is returned by Tx.Commit when dynamoDB cancels the
+// transaction, with the failures of the operations that caused it.
+type TxCanceledError struct {
+	Failures []TxFailure
+	Err      error
+}
+
+func (e *TxCanceledError) Error() string {
+	fs := make([]string, 0, len(e.Failures))
+	for _, f := range e.Failures {
+		fs = append(fs, f.Op.String()+": "+f.Code)
+	}
+	return "dynaGo: transaction canceled: " + strings.Join(fs, "; ")
+}
+
+// Unwrap returns the TransactionCanceledException of dynamoDB.
+func (e *TxCanceledError) Unwrap() error {
+	return e.Err
+}
+
+// UniqueConstraintError is returned when a write would give a "unique"
+// attribute a value another item already holds.
+type UniqueConstraintError struct {
+	TableName string
+	Attribute string
+	Value     string
+}
+
+func (e *UniqueConstraintError) Error() string {
+	return "dynaGo: " + e.TableName + " " + e.Attribute + " " + e.Value + " is already taken"
+}
@@ -0,0 +1,94 @@
+// Copyright 2016 Appittome. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dynaGo
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// expression collects the placeholder maps shared by the condition and
+// update expressions of a single request.  Attribute names are aliased
+// so that they never collide with dynamoDB reserved words.
+type expression struct {
+	names  map[string]*string
+	values map[string]*dynamodb.AttributeValue
+	alias  map[string]string
+}
+
+func newExpression() *expression {
+	return &expression{
+		names:  make(map[string]*string),
+		values: make(map[string]*dynamodb.AttributeValue),
+		alias:  make(map[string]string),
+	}
+}
+
+// continues the expression whose placeholders are names and values,
+// so further placeholders do not collide with them
+func expressionFrom(names map[string]*string, values map[string]*dynamodb.AttributeValue) *expression {
+	x := newExpression()
+	for p, n := range names {
+		x.names[p] = n
+		x.alias[*n] = p
+	}
+	for p, av := range values {
+		x.values[p] = av
+	}
+	return x
+}
+
+// name returns the placeholder for attribute name n, reusing the
+// placeholder if n has been seen before.
+func (x *expression) name(n string) string {
+	if p, ok := x.alias[n]; ok {
+		return p
+	}
+	p, an := "#n"+strconv.Itoa(len(x.names)), n
+	x.names[p] = &an
+	x.alias[n] = p
+	return p
+}
+
+// value returns a fresh placeholder bound to av
+func (x *expression) value(av *dynamodb.AttributeValue) string {
+	p := ":v" + strconv.Itoa(len(x.values))
+	x.values[p] = av
+	return p
+}
+
+// the SDK rejects empty placeholder maps, so these return nil instead
+func (x *expression) attributeNames() map[string]*string {
+	if len(x.names) == 0 {
+		return nil
+	}
+	return x.names
+}
+func (x *expression) attributeValues() map[string]*dynamodb.AttributeValue {
+	if len(x.values) == 0 {
+		return nil
+	}
+	return x.values
+}
+
+// appends condition c to an optional condition expression
+func andCondition(ce *string, c string) *string {
+	if ce == nil {
+		return &c
+	}
+	s := "(" + *ce + ") AND (" + c + ")"
+	return &s
+}
+
+// joins conditions with AND, returns nil if there are none
+func conditionExpression(cs []string) *string {
+	if len(cs) == 0 {
+		return nil
+	}
+	s := strings.Join(cs, " AND ")
+	return &s
+}
@@ -0,0 +1,256 @@
+// Copyright 2016 Appittome. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dynaGo
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// In single table designs several entity types overload the same global
+// secondary index, each with its own sparse, composed index keys (see
+// compose.go):
+//   type Order struct {
+//       ...
+//       OrgId   string
+//       At      time.Time
+//       GSI1PK  string `dynaGo:",gsi=GSI1,compose=ORG#OrgId,sparse"`
+//       GSI1SK  string `dynaGo:",gsirange=GSI1,compose=ORDER#At,sparse"`
+//   }
+// An IndexQuery queries such an index with the key values of the type
+// declaring it, and decodes the results into that type:
+//   it := dynaGo.NewIndexQuery(Order{}, "GSI1").
+//       Partition("o42").SortBeginsWith().Iterator(ctx, svc)
+// Like a KeyMaker, a composed key takes its component values (or the
+// composed string), and prefixes are added.  If the type is registered,
+// items of other entity types are filtered out, unless AllEntities is
+// set.
+
+// IndexQuery builds a Query on a global secondary index of a type.
+type IndexQuery struct {
+	typ      reflect.Type
+	index    string
+	keys     map[string]string // attribute names by key type
+	types    map[string]string // attribute types by name
+	x        *expression
+	conds    []string
+	hashed   bool
+	entities bool
+	coercion Coercion
+	err      error
+}
+
+// NewIndexQuery starts a query on the index of the struct type of v.
+func NewIndexQuery(v interface{}, index string) *IndexQuery {
+	t := reflectType(v)
+	q := &IndexQuery{typ: t, index: index, keys: make(map[string]string), x: newExpression()}
+	in := typeSchema(t)
+	for _, gsi := range in.GlobalSecondaryIndexes {
+		if *gsi.IndexName != index {
+			continue
+		}
+		for _, ks := range gsi.KeySchema {
+			q.keys[*ks.KeyType] = *ks.AttributeName
+		}
+	}
+	if len(q.keys) == 0 {
+		q.err = &UnknownIndexError{t, index}
+	}
+	q.types = attributeDefinitionMap(in.AttributeDefinitions)
+	return q
+}
+
+// Partition selects the items whose index partition key is kv.
+func (q *IndexQuery) Partition(kv ...interface{}) *IndexQuery {
+	q.hashed = true
+	return q.condition(dynamodb.KeyTypeHash, "%s = %s", kv)
+}
+
+// SortEquals selects the items whose index sort key is kv.
+func (q *IndexQuery) SortEquals(kv ...interface{}) *IndexQuery {
+	return q.condition(dynamodb.KeyTypeRange, "%s = %s", kv)
+}
+
+// SortBeginsWith selects the items whose index sort key begins with kv,
+// for a composed key the leading component values, which may be none.
+func (q *IndexQuery) SortBeginsWith(kv ...interface{}) *IndexQuery {
+	n, ok := q.keyName(dynamodb.KeyTypeRange)
+	if !ok {
+		return q
+	}
+	if q.types[n] != dynamodb.ScalarAttributeTypeS {
+		q.err = fmt.Errorf("dynaGo:%s IndexQuery: %s is not a string", q.typ.Name(), n)
+		return q
+	}
+	f, _ := q.field(n)
+	var s string
+	c := getComposition(q.typ, f)
+	switch {
+	case c != nil:
+		s, q.err = c.composePrefix(kv, q.coercion)
+	case len(kv) != 1:
+		q.err = fmt.Errorf("dynaGo:%s IndexQuery: %s takes 1 value, found %d", q.typ.Name(), n, len(kv))
+	default:
+		var av dynamodb.AttributeValue
+		// a prefixed number is a padded string
+		if av, q.err = createAttribute(f, kv[0], q.coercion); q.err == nil {
+			s = *addPrefix("", f.Type, &av).S
+		}
+	}
+	if q.err == nil {
+		s = getPrefix(f) + s
+		q.conds = append(q.conds, "begins_with("+q.x.name(n)+", "+q.x.value(&dynamodb.AttributeValue{S: &s})+")")
+	}
+	return q
+}
+
+// SortBetween selects the items whose index sort key is between lo and
+// hi, inclusive.  A composed key takes the composed strings.
+func (q *IndexQuery) SortBetween(lo, hi interface{}) *IndexQuery {
+	n, ok := q.keyName(dynamodb.KeyTypeRange)
+	if !ok {
+		return q
+	}
+	l, ok := q.value(n, []interface{}{lo})
+	if !ok {
+		return q
+	}
+	h, ok := q.value(n, []interface{}{hi})
+	if !ok {
+		return q
+	}
+	q.conds = append(q.conds, q.x.name(n)+" BETWEEN "+q.x.value(l)+" AND "+q.x.value(h))
+	return q
+}
+
+// Coerce converts the key values given to the conditions that follow as
+// c allows, none are converted by default.
+func (q *IndexQuery) Coerce(c Coercion) *IndexQuery {
+	q.coercion = c
+	return q
+}
+
+// AllEntities decodes the items of every registered type sharing the
+// index with UnmarshalEntity, instead of only those of the queried type.
+func (q *IndexQuery) AllEntities() *IndexQuery {
+	q.entities = true
+	return q
+}
+
+// Input returns the QueryInput, or the first error met while building
+// it.  A partition key is required.
+func (q *IndexQuery) Input() (*dynamodb.QueryInput, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+	if !q.hashed {
+		return nil, fmt.Errorf("dynaGo:%s IndexQuery: missing partition key", q.typ.Name())
+	}
+	x := expressionFrom(q.x.names, q.x.values)
+	var filter *string
+	if e, ok := registered(q.typ); ok && !q.entities {
+		name := e.name
+		filter = aws.String(x.name(TypeAttribute) + " = " + x.value(&dynamodb.AttributeValue{S: &name}))
+	}
+	return &dynamodb.QueryInput{
+		TableName:                 aws.String(TableName(q.typ)),
+		IndexName:                 aws.String(q.index),
+		KeyConditionExpression:    conditionExpression(q.conds),
+		FilterExpression:          filter,
+		ExpressionAttributeNames:  x.attributeNames(),
+		ExpressionAttributeValues: x.attributeValues(),
+	}, nil
+}
+
+// Iterator runs the query, decoding the results into new values of the
+// queried type, or of their registered types with AllEntities.  opts
+// apply to every Query request.
+func (q *IndexQuery) Iterator(ctx aws.Context, svc dynamodbiface.DynamoDBAPI, opts ...request.Option) *Iterator {
+	in, err := q.Input()
+	if err != nil {
+		it := newIterator(ctx, nil, nil, nil, nil, q.index)
+		it.err = err
+		return it
+	}
+	var v interface{}
+	if !q.entities {
+		v = reflect.Zero(q.typ).Interface()
+	}
+	return NewQueryIterator(ctx, svc, in, v, opts...)
+}
+
+// adds the condition format f on the key of type kt being kv
+func (q *IndexQuery) condition(kt, f string, kv []interface{}) *IndexQuery {
+	n, ok := q.keyName(kt)
+	if !ok {
+		return q
+	}
+	if av, ok := q.value(n, kv); ok {
+		q.conds = append(q.conds, fmt.Sprintf(f, q.x.name(n), q.x.value(av)))
+	}
+	return q
+}
+
+// the index key attribute of type kt, false if the query failed already
+// or the index has none
+func (q *IndexQuery) keyName(kt string) (string, bool) {
+	if q.err != nil {
+		return "", false
+	}
+	n, ok := q.keys[kt]
+	if !ok {
+		q.err = fmt.Errorf("dynaGo:%s IndexQuery: index %s has no %s key", q.typ.Name(), q.index, kt)
+	}
+	return n, ok
+}
+
+// the value of the key attribute n made of kv, as a KeyMaker makes it
+func (q *IndexQuery) value(n string, kv []interface{}) (*dynamodb.AttributeValue, bool) {
+	f, i := q.field(n)
+	if len(kv) == 0 {
+		q.err = fmt.Errorf("dynaGo:%s IndexQuery: missing value for %s", q.typ.Name(), n)
+		return nil, false
+	}
+	v, rest, err := keyValue(q.typ, f, kv, true, q.coercion)
+	if err == nil && len(rest) > 0 {
+		err = fmt.Errorf("dynaGo:%s IndexQuery: %s takes 1 value, found %d", q.typ.Name(), n, len(kv))
+	}
+	if err != nil {
+		q.err = err
+		return nil, false
+	}
+	_, av, err := getKeynameAndAttribute(q.typ, i, v, q.coercion)
+	if err != nil {
+		q.err = err
+		return nil, false
+	}
+	return &av, true
+}
+
+// the top level field of attribute n, and the path to the value of its
+// key, through nested structs as for the primary key
+func (q *IndexQuery) field(n string) (reflect.StructField, []int) {
+	for i := 0; i < q.typ.NumField(); i++ {
+		f := q.typ.Field(i)
+		if getAttrName(f) != n {
+			continue
+		}
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if ft.Kind() == reflect.Struct && ft != timeType {
+			return f, append([]int{i}, getKeyAttributePath(ft, dynamodb.KeyTypeHash)...)
+		}
+		return f, []int{i}
+	}
+	// the index was found by encoding t, so its attributes are fields
+	panic(&MissingKeyError{q.typ, n})
+}
@@ -0,0 +1,147 @@
+// Copyright 2016 Appittome. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dynaGo
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/japhyf/dynaGo/dynagotest"
+)
+
+type Employee struct {
+	PK     string `dynaGo:",HASH"`
+	SK     string `dynaGo:",RANGE"`
+	OrgId  string
+	Name   string
+	GSI1PK string `dynaGo:",gsi=GSI1,compose=ORG#OrgId,sparse"`
+	GSI1SK string `dynaGo:",gsirange=GSI1,compose=EMP#Name,sparse"`
+}
+
+type Invoice struct {
+	PK     string `dynaGo:",HASH"`
+	SK     string `dynaGo:",RANGE"`
+	OrgId  string
+	At     time.Time
+	GSI1PK string `dynaGo:",gsi=GSI1,compose=ORG#OrgId,sparse"`
+	GSI1SK string `dynaGo:",gsirange=GSI1,compose=INV#At,sparse"`
+}
+
+func init() {
+	Register("Org", Employee{})
+	Register("Org", Invoice{})
+}
+
+func TestSparseIndex(t *testing.T) {
+	in := Marshal(Employee{PK: "e1", SK: "e1", OrgId: "o1"})
+	if _, ok := in.Item["GSI1SK"]; ok {
+		t.Errorf("expected no sparse attribute, found %v", in.Item["GSI1SK"])
+	}
+	if pk := in.Item["GSI1PK"]; pk == nil || *pk.S != "ORG#o1" {
+		t.Errorf("unexpected composed attribute: %v", pk)
+	}
+	in = Marshal(Employee{PK: "e1", SK: "e1", OrgId: "o1", Name: "ann", GSI1PK: "stale"})
+	if sk := in.Item["GSI1SK"]; sk == nil || *sk.S != "EMP#ann" {
+		t.Errorf("unexpected composed attribute: %v", sk)
+	}
+	if _, ok := Marshal(Employee{PK: "e1", SK: "e1", GSI1PK: "stale"}).Item["GSI1PK"]; ok {
+		t.Error("expected the sparse attribute to be left out")
+	}
+}
+
+func TestIndexQuery(t *testing.T) {
+	db := dynagotest.New()
+	if err := CreateTable(db, Employee{}, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2016, 8, 1, 0, 0, 0, 0, time.UTC)
+	for _, v := range []interface{}{
+		Employee{PK: "e1", SK: "e1", OrgId: "o1", Name: "ann"},
+		Employee{PK: "e2", SK: "e2", OrgId: "o1", Name: "bob"},
+		Employee{PK: "e3", SK: "e3", OrgId: "o1"},
+		Employee{PK: "e4", SK: "e4", OrgId: "o2", Name: "cat"},
+		Invoice{PK: "i1", SK: "i1", OrgId: "o1", At: at},
+	} {
+		if err := Put(db, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx := aws.BackgroundContext()
+
+	var names []string
+	it := NewIndexQuery(Employee{}, "GSI1").Partition("o1").Iterator(ctx, db)
+	for it.Next() {
+		names = append(names, it.Item().(*Employee).Name)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 || names[0] != "ann" || names[1] != "bob" {
+		t.Errorf("unexpected employees: %v", names)
+	}
+
+	it = NewIndexQuery(Employee{}, "GSI1").Partition("o1").SortBeginsWith("b").Iterator(ctx, db)
+	if !it.Next() || it.Item().(*Employee).Name != "bob" || it.Next() {
+		t.Errorf("expected bob alone, err %v", it.Err())
+	}
+
+	var n int
+	it = NewIndexQuery(Invoice{}, "GSI1").Partition("o1").AllEntities().Iterator(ctx, db)
+	for it.Next() {
+		n++
+	}
+	if err := it.Err(); err != nil || n != 3 {
+		t.Errorf("expected 3 entities, found %d, err %v", n, err)
+	}
+
+	it = NewIndexQuery(Invoice{}, "GSI1").Partition("o1").SortEquals(at).Iterator(ctx, db)
+	if !it.Next() || !it.Item().(*Invoice).At.Equal(at) || it.Next() {
+		t.Errorf("expected the invoice alone, err %v", it.Err())
+	}
+
+	if _, err := NewIndexQuery(Employee{}, "GSI2").Partition("o1").Input(); err == nil {
+		t.Error("expected an error for an unknown index")
+	}
+	if _, err := NewIndexQuery(Employee{}, "GSI1").SortEquals("ann").Input(); err == nil {
+		t.Error("expected an error without a partition key")
+	}
+}
+
+func TestSparseUpdateLeavesIndex(t *testing.T) {
+	up := MarshalUpdate(Employee{PK: "e1", SK: "e1", OrgId: "o1"})
+	if !strings.HasSuffix(*up.UpdateExpression, " REMOVE #n3") || *up.ExpressionAttributeNames["#n3"] != "GSI1SK" {
+		t.Errorf("expected the sparse attribute to be removed: %s %v", *up.UpdateExpression, up.ExpressionAttributeNames)
+	}
+
+	db := dynagotest.New()
+	if err := CreateTable(db, Employee{}, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := Put(db, Employee{PK: "e1", SK: "e1", OrgId: "o1", Name: "ann"}); err != nil {
+		t.Fatal(err)
+	}
+	count := func() int {
+		n := 0
+		it := NewIndexQuery(Employee{}, "GSI1").Partition("o1").Iterator(aws.BackgroundContext(), db)
+		for it.Next() {
+			n++
+		}
+		if err := it.Err(); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	if n := count(); n != 1 {
+		t.Fatalf("expected the employee in the index, found %d", n)
+	}
+	if err := Update(db, Employee{PK: "e1", SK: "e1", OrgId: "o1"}); err != nil {
+		t.Fatal(err)
+	}
+	if n := count(); n != 0 {
+		t.Errorf("expected the employee to leave the index, found %d", n)
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2016 Appittome. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package wait polls with exponential backoff, for the table waits of
+// dynaGo and dynagov2.
+package wait
+
+import (
+	"context"
+	"time"
+)
+
+// used in place of zero delays
+const (
+	DefaultDelay    = 500 * time.Millisecond
+	DefaultMaxDelay = 20 * time.Second
+)
+
+// Backoff describes the polling of Poll.  The delay between polls starts
+// at Delay and doubles up to MaxDelay.  A zero Timeout waits until the
+// context is done.  It has the fields of dynaGo.WaitOptions, so either
+// converts to the other.
+type Backoff struct {
+	Delay    time.Duration
+	MaxDelay time.Duration
+	Timeout  time.Duration
+}
+
+// b with the default delays in place of zero ones
+func (b Backoff) withDefaults() Backoff {
+	if b.Delay <= 0 {
+		b.Delay = DefaultDelay
+	}
+	if b.MaxDelay <= 0 {
+		b.MaxDelay = DefaultMaxDelay
+	}
+	return b
+}
+
+// the delay after the poll-th poll, counting from 0
+func (b Backoff) delay(poll int) time.Duration {
+	d := b.Delay << uint(poll)
+	if d > b.MaxDelay || d <= 0 || poll > 62 {
+		d = b.MaxDelay
+	}
+	return d
+}
+
+// Poll calls check until it reports done or fails, sleeping between
+// calls as described by b.  check is handed ctx, limited to b.Timeout.
+// A check failing once ctx is done reports the error of ctx, so a
+// timeout is reported the same whether it expires during a check or
+// between two.
+func Poll(ctx context.Context, b Backoff, check func(ctx context.Context) (done bool, err error)) error {
+	b = b.withDefaults()
+	if b.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, b.Timeout)
+		defer cancel()
+	}
+	for poll := 0; ; poll++ {
+		done, err := check(ctx)
+		if err != nil && ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if done || err != nil {
+			return err
+		}
+		if err := Sleep(ctx, b.delay(poll)); err != nil {
+			return err
+		}
+	}
+}
+
+// Sleep waits for d, or until ctx is done.
+func Sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2016 Appittome. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wait
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDelay(t *testing.T) {
+	if b := (Backoff{}).withDefaults(); b.Delay != DefaultDelay || b.MaxDelay != DefaultMaxDelay {
+		t.Errorf("expected the defaults, found %v", b)
+	}
+	b := Backoff{Delay: time.Millisecond, MaxDelay: 4 * time.Millisecond}.withDefaults()
+	for poll, d := range []time.Duration{1, 2, 4, 4, 4} {
+		if b.delay(poll) != d*time.Millisecond {
+			t.Errorf("unexpected delay %v after poll %d", b.delay(poll), poll)
+		}
+	}
+	if b.delay(100) != b.MaxDelay {
+		t.Errorf("expected the delay to stay capped, found %v", b.delay(100))
+	}
+}
+
+func TestPoll(t *testing.T) {
+	b := Backoff{Delay: time.Millisecond}
+	n := 0
+	err := Poll(context.Background(), b, func(ctx context.Context) (bool, error) {
+		n++
+		return n == 3, nil
+	})
+	if err != nil || n != 3 {
+		t.Errorf("expected 3 checks, found %d, %v", n, err)
+	}
+
+	fail := errors.New("failed")
+	n = 0
+	err = Poll(context.Background(), b, func(ctx context.Context) (bool, error) {
+		n++
+		return false, fail
+	})
+	if err != fail || n != 1 {
+		t.Errorf("expected the first error, found %v after %d checks", err, n)
+	}
+
+	b.Timeout = 10 * time.Millisecond
+	err = Poll(context.Background(), b, func(ctx context.Context) (bool, error) {
+		return false, nil
+	})
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected the wait to time out, found %v", err)
+	}
+	// a check failing because the timeout expired during it
+	err = Poll(context.Background(), b, func(ctx context.Context) (bool, error) {
+		<-ctx.Done()
+		return false, fail
+	})
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected the wait to time out, found %v", err)
+	}
+}
@@ -0,0 +1,218 @@
+// Copyright 2016 Appittome. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dynaGo
+
+import (
+	"reflect"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+type pageFetcher func(ctx aws.Context, start map[string]*dynamodb.AttributeValue, limit *int64) (
+	items []map[string]*dynamodb.AttributeValue, last map[string]*dynamodb.AttributeValue, err error)
+
+// Iterator reads the items of a Query or Scan one at a time, fetching
+// the next page whenever dynamoDB returns a LastEvaluatedKey, and
+// decoding every item with Unmarshal:
+//   it := NewQueryIterator(ctx, svc, qi, Message{})
+//   for it.Next() {
+//       msg := it.Item().(*Message)
+//   }
+//   if err := it.Err(); err != nil {...}
+type Iterator struct {
+	ctx   aws.Context
+	fetch pageFetcher
+	typ   reflect.Type
+	index string
+
+	limit    int
+	pageSize int64
+
+	page  []map[string]*dynamodb.AttributeValue
+	pos   int
+	count int
+	last  map[string]*dynamodb.AttributeValue
+	done  bool
+	item  interface{}
+	err   error
+}
+
+// NewQueryIterator iterates over the results of in, decoding them into
+// new values of the type of v, or with UnmarshalEntity when v is nil.
+// Items of other types registered to the table of v are skipped.
+// in is copied, so it is not modified, its Limit is used as the page
+// size.  opts apply to every Query request.
+func NewQueryIterator(ctx aws.Context, svc dynamodbiface.DynamoDBAPI, in *dynamodb.QueryInput, v interface{}, opts ...request.Option) *Iterator {
+	q := *in
+	fetch := func(ctx aws.Context, start map[string]*dynamodb.AttributeValue, limit *int64) (
+		[]map[string]*dynamodb.AttributeValue, map[string]*dynamodb.AttributeValue, error) {
+		q.ExclusiveStartKey, q.Limit = start, limit
+		resp, err := svc.QueryWithContext(ctx, &q, requestOptions(ctx, opts)...)
+		if err != nil {
+			return nil, nil, err
+		}
+		return resp.Items, resp.LastEvaluatedKey, nil
+	}
+	return newIterator(ctx, fetch, v, in.ExclusiveStartKey, in.Limit, aws.StringValue(in.IndexName))
+}
+
+// NewScanIterator iterates over the results of in, decoding them into
+// new values of the type of v, or with UnmarshalEntity when v is nil.
+// Items of other types registered to the table of v are skipped.
+// in is copied, so it is not modified, its Limit is used as the page
+// size.  opts apply to every Scan request.
+func NewScanIterator(ctx aws.Context, svc dynamodbiface.DynamoDBAPI, in *dynamodb.ScanInput, v interface{}, opts ...request.Option) *Iterator {
+	s := *in
+	fetch := func(ctx aws.Context, start map[string]*dynamodb.AttributeValue, limit *int64) (
+		[]map[string]*dynamodb.AttributeValue, map[string]*dynamodb.AttributeValue, error) {
+		s.ExclusiveStartKey, s.Limit = start, limit
+		resp, err := svc.ScanWithContext(ctx, &s, requestOptions(ctx, opts)...)
+		if err != nil {
+			return nil, nil, err
+		}
+		return resp.Items, resp.LastEvaluatedKey, nil
+	}
+	return newIterator(ctx, fetch, v, in.ExclusiveStartKey, in.Limit, aws.StringValue(in.IndexName))
+}
+
+func newIterator(ctx aws.Context, fetch pageFetcher, v interface{}, start map[string]*dynamodb.AttributeValue, pageSize *int64, index string) *Iterator {
+	it := &Iterator{
+		ctx:      ctx,
+		fetch:    fetch,
+		index:    index,
+		pageSize: aws.Int64Value(pageSize),
+		last:     start,
+	}
+	if v != nil {
+		it.typ = reflectType(v)
+	}
+	return it
+}
+
+// SetLimit stops the iterator after n items, n <= 0 means no limit.
+func (it *Iterator) SetLimit(n int) *Iterator {
+	it.limit = n
+	return it
+}
+
+// SetPageSize sets the Limit of each request to n items.
+func (it *Iterator) SetPageSize(n int64) *Iterator {
+	it.pageSize = n
+	return it
+}
+
+// Next advances to the next item, fetching a new page if needed.  It
+// returns false when the results or the limit are exhausted, or when an
+// error occured, which is then returned by Err.
+func (it *Iterator) Next() bool {
+	if it.err != nil || (it.limit > 0 && it.count >= it.limit) {
+		return false
+	}
+	// unique sentinels share the table, but are not items, and items of
+	// other types sharing it are not of a typed iterator
+	for it.pos >= len(it.page) || it.skip(it.page[it.pos]) {
+		if it.pos < len(it.page) {
+			it.pos++
+			continue
+		}
+		if it.done {
+			return false
+		}
+		if it.err = it.ctx.Err(); it.err != nil {
+			return false
+		}
+		start := it.last
+		it.page, it.last, it.err = it.fetch(it.ctx, start, it.requestLimit())
+		if it.err != nil {
+			it.last = start
+			return false
+		}
+		it.pos, it.done = 0, len(it.last) == 0
+	}
+	if it.item, it.err = it.decode(it.page[it.pos]); it.err != nil {
+		return false
+	}
+	it.pos++
+	it.count++
+	return true
+}
+
+// whether item is left out of the results
+func (it *Iterator) skip(item map[string]*dynamodb.AttributeValue) bool {
+	return isSentinel(item) || it.typ != nil && !isEntityOf(it.typ, item)
+}
+
+func (it *Iterator) decode(item map[string]*dynamodb.AttributeValue) (interface{}, error) {
+	if it.typ == nil {
+		return UnmarshalEntity(item)
+	}
+	o := reflect.New(it.typ).Interface()
+	return o, Unmarshal(item, o)
+}
+
+// Item returns the current item, a pointer to the type given to the
+// iterator, or to the registered type of the item.
+func (it *Iterator) Item() interface{} {
+	return it.item
+}
+
+// Err returns the error that stopped the iterator, if any.
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+// LastEvaluatedKey returns the key to start after to continue with the
+// item following the current one, or nil if there are no more items.
+// If the iterator stopped part way through a page, the key is taken
+// from the current item.
+func (it *Iterator) LastEvaluatedKey() map[string]*dynamodb.AttributeValue {
+	if it.item != nil && it.pos > 0 && it.pos < len(it.page) {
+		return itemKey(reflectType(it.item), it.index, it.page[it.pos-1])
+	}
+	return it.last
+}
+
+// the page size, shrunk to what is left of the limit
+func (it *Iterator) requestLimit() *int64 {
+	n := it.pageSize
+	if it.limit > 0 {
+		if r := int64(it.limit - it.count); n == 0 || r < n {
+			n = r
+		}
+	}
+	if n == 0 {
+		return nil
+	}
+	return &n
+}
+
+// the key attributes of item, including those of index when it is set,
+// as dynamoDB would return them in a LastEvaluatedKey.
+func itemKey(t reflect.Type, index string, item map[string]*dynamodb.AttributeValue) map[string]*dynamodb.AttributeValue {
+	k := make(map[string]*dynamodb.AttributeValue)
+	for _, n := range append(keyAttrNames(t), indexKeyAttrNames(t, index)...) {
+		if av, ok := item[n]; ok {
+			k[n] = av
+		}
+	}
+	return k
+}
+
+// the key attribute names of the global secondary index in of t
+func indexKeyAttrNames(t reflect.Type, in string) []string {
+	if in == "" {
+		return nil
+	}
+	e := newTableEncoderState()
+	encode(e, reflect.Zero(t).Interface())
+	ns := make([]string, 0)
+	for _, ks := range e.indexes[in] {
+		ns = append(ns, *ks.AttributeName)
+	}
+	return ns
+}
@@ -0,0 +1,115 @@
+// Copyright 2016 Appittome. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dynaGo
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/japhyf/dynaGo/dynagotest"
+)
+
+// records the Limit of the Query requests made through it
+type queryRecorder struct {
+	*dynagotest.DB
+	limits []int64
+}
+
+func (r *queryRecorder) QueryWithContext(ctx aws.Context, in *dynamodb.QueryInput, opts ...request.Option) (*dynamodb.QueryOutput, error) {
+	r.limits = append(r.limits, aws.Int64Value(in.Limit))
+	return r.DB.QueryWithContext(ctx, in, opts...)
+}
+
+// a session of 10 messages, with Timestamps 1 to 10
+func newSession(t *testing.T) (*queryRecorder, *dynamodb.QueryInput) {
+	db := dynagotest.New()
+	if err := CreateTable(db, Message{}, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	for i := int64(1); i <= 10; i++ {
+		if err := Put(db, Message{SessId: "s1", Timestamp: i, Body: "hi"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	qi := &dynamodb.QueryInput{
+		TableName:                 aws.String("Messages"),
+		KeyConditionExpression:    aws.String("SessionId = :s"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":s": {S: aws.String("s1")}},
+	}
+	return &queryRecorder{DB: db}, qi
+}
+
+// the Timestamps of the messages read by it
+func timestamps(it *Iterator) []int64 {
+	var ts []int64
+	for it.Next() {
+		ts = append(ts, it.Item().(*Message).Timestamp)
+	}
+	return ts
+}
+
+func TestIteratorLimit(t *testing.T) {
+	r, qi := newSession(t)
+	it := NewQueryIterator(aws.BackgroundContext(), r, qi, Message{}).SetPageSize(3).SetLimit(7)
+	ts := timestamps(it)
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ts, []int64{1, 2, 3, 4, 5, 6, 7}) {
+		t.Errorf("unexpected messages %v", ts)
+	}
+	// the last page only asks for what is left of the limit
+	if !reflect.DeepEqual(r.limits, []int64{3, 3, 1}) {
+		t.Errorf("unexpected page sizes %v", r.limits)
+	}
+	if it.LastEvaluatedKey() == nil {
+		t.Error("expected a key to continue after the limit")
+	}
+}
+
+func TestIteratorResume(t *testing.T) {
+	r, qi := newSession(t)
+	it := NewQueryIterator(aws.BackgroundContext(), r, qi, Message{}).SetPageSize(4)
+	for i := 0; i < 6 && it.Next(); i++ {
+	}
+	// stopped part way through the second page
+	last := it.LastEvaluatedKey()
+	if n := *last["Timestamp"].N; n != "6" || len(last) != 2 {
+		t.Fatalf("unexpected last key %v", last)
+	}
+	in := *qi
+	in.ExclusiveStartKey = last
+	ts := timestamps(NewQueryIterator(aws.BackgroundContext(), r, &in, Message{}).SetPageSize(4))
+	if !reflect.DeepEqual(ts, []int64{7, 8, 9, 10}) {
+		t.Errorf("unexpected messages after resuming %v", ts)
+	}
+	if it = NewQueryIterator(aws.BackgroundContext(), r, &in, Message{}); len(timestamps(it)) != 4 || it.LastEvaluatedKey() != nil {
+		t.Errorf("expected no key once the results are exhausted, found %v", it.LastEvaluatedKey())
+	}
+}
+
+func TestIteratorCanceled(t *testing.T) {
+	r, qi := newSession(t)
+	ctx, cancel := context.WithCancel(aws.BackgroundContext())
+	defer cancel()
+	it := NewQueryIterator(ctx, r, qi, Message{}).SetPageSize(2)
+	n := 0
+	for it.Next() {
+		if n++; n == 1 {
+			cancel()
+		}
+	}
+	// the rest of the page is read, the next page is not fetched
+	if n != 2 || it.Err() != context.Canceled {
+		t.Errorf("expected the iterator to stop after the page, read %d, err %v", n, it.Err())
+	}
+	if len(r.limits) != 1 {
+		t.Errorf("expected one request, found %d", len(r.limits))
+	}
+}
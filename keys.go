@@ -11,7 +11,10 @@ import (
 	"runtime"
 	"strconv"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
 )
 
 // This part of the package is somewhat ad-hoc and disorganized.
@@ -22,11 +25,22 @@ type key struct {
 	pkn  string
 	rkn  string
 	tbln string
+	typ  reflect.Type
 	attr map[string]*dynamodb.AttributeValue
 }
 
 type KeyMaker func(...interface{}) (key, error)
 
+// TableName is the name of the table the key belongs to.
+func (k key) TableName() string {
+	return k.tbln
+}
+
+// Attributes are the key attributes, as used by GetItemInput.Key.
+func (k key) Attributes() map[string]*dynamodb.AttributeValue {
+	return k.attr
+}
+
 // To put items to dynamoDB is one thing (Marshal), but to get items from
 // dynamoDB often requires a GetItemInput (if the item is fetched by primary key directly)
 // this method will convert a struct i with a key value ...k [partition key, rangekey]
@@ -36,6 +50,12 @@ type KeyMaker func(...interface{}) (key, error)
 // This method may have some logical overlap with encode()
 // should look into that someday.  May just be able to grab the KeySchema?
 func CreateKeyMaker(rt reflect.Type) KeyMaker {
+	return CreateKeyMakerWithCoercion(rt, CoerceNone)
+}
+
+// CreateKeyMakerWithCoercion is CreateKeyMaker converting key values of
+// another kind than their key field as c allows.
+func CreateKeyMakerWithCoercion(rt reflect.Type, c Coercion) KeyMaker {
 	//allow pointers to struct
 	var t reflect.Type
 	switch rt.Kind() {
@@ -47,11 +67,12 @@ func CreateKeyMaker(rt reflect.Type) KeyMaker {
 
 	priK := key{
 		tbln: TableName(t),
+		typ:  t,
 	}
 	//partition key, panics if not found
 	pki := getPartitionKey(t)
 	pF := func(kv interface{}) (string, dynamodb.AttributeValue, error) {
-		return getKeynameAndAttribute(t, pki, kv)
+		return getKeynameAndAttribute(t, pki, kv, c)
 	}
 
 	//range key may not exist
@@ -63,7 +84,11 @@ func CreateKeyMaker(rt reflect.Type) KeyMaker {
 				es := fmt.Sprintf("dynaGo:%s KeyMaker: incorrect num args [%d]", t.Name(), len(ks))
 				return key{}, errors.New(es)
 			}
-			k, v, err := pF(ks[0])
+			kv, _, err := keyValue(t, t.Field(pki[0]), ks, true, c)
+			if err != nil {
+				return key{}, err
+			}
+			k, v, err := pF(kv)
 			if err != nil {
 				return key{}, err
 			}
@@ -75,7 +100,7 @@ func CreateKeyMaker(rt reflect.Type) KeyMaker {
 		}
 	}
 	rF := func(rk interface{}) (string, dynamodb.AttributeValue, error) {
-		return getKeynameAndAttribute(t, rki, rk)
+		return getKeynameAndAttribute(t, rki, rk, c)
 	}
 
 	return func(ks ...interface{}) (key, error) {
@@ -83,7 +108,15 @@ func CreateKeyMaker(rt reflect.Type) KeyMaker {
 			es := fmt.Sprintf("dynaGo:%s KeyMaker: incorrect num args [%d]", t.Name(), len(ks))
 			return key{}, errors.New(es)
 		}
-		pk, pv, err := pF(ks[0])
+		kv, ks, err := keyValue(t, t.Field(pki[0]), ks, false, c)
+		if err != nil {
+			return key{}, err
+		}
+		if len(ks) < 1 {
+			es := fmt.Sprintf("dynaGo:%s KeyMaker: missing range key", t.Name())
+			return key{}, errors.New(es)
+		}
+		pk, pv, err := pF(kv)
 		if err != nil {
 			return key{}, err
 		}
@@ -91,7 +124,10 @@ func CreateKeyMaker(rt reflect.Type) KeyMaker {
 		priK.attr = make(map[string]*dynamodb.AttributeValue)
 		priK.attr[pk] = &pv
 
-		rk, rv, err := rF(ks[1])
+		if kv, _, err = keyValue(t, t.Field(rki[0]), ks, true, c); err != nil {
+			return key{}, err
+		}
+		rk, rv, err := rF(kv)
 		if err != nil {
 			return key{}, err
 		}
@@ -101,6 +137,92 @@ func CreateKeyMaker(rt reflect.Type) KeyMaker {
 	}
 }
 
+// KeyFrom returns the key of the struct (or pointer to struct) i, made
+// of the values of its HASH and RANGE fields, followed through nested
+// structs and pointers as the table schema does.  A composed key is
+// composed from its component fields, unless they are all zero.
+func KeyFrom(i interface{}) (key, error) {
+	v := reflect.Indirect(reflect.ValueOf(i))
+	if v.Kind() != reflect.Struct {
+		return key{}, &OnlyStructsSupportedError{v.Kind()}
+	}
+	t := v.Type()
+	k := key{
+		tbln: TableName(t),
+		typ:  t,
+		attr: make(map[string]*dynamodb.AttributeValue),
+	}
+	paths := [][]int{getPartitionKey(t)}
+	if rki, err := getRangeKey(t); err == nil {
+		paths = append(paths, rki)
+	}
+	for n, path := range paths {
+		kv, err := keyFieldValue(v, path)
+		if err != nil {
+			return key{}, err
+		}
+		kn, ka, err := getKeynameAndAttribute(t, path, kv, CoerceNone)
+		if err != nil {
+			return key{}, err
+		}
+		if n == 0 {
+			k.pkn = kn
+		} else {
+			k.rkn = kn
+		}
+		k.attr[kn] = &ka
+	}
+	return k, nil
+}
+
+// the value at path i of the struct v, or the composed value if the key
+// field is composed.  Nil pointers on the way are an error.
+func keyFieldValue(v reflect.Value, i []int) (interface{}, error) {
+	if c := getComposition(v.Type(), v.Type().Field(i[0])); c != nil {
+		if s, any, _ := c.compose(v); any {
+			return s, nil
+		}
+	}
+	t := v.Type()
+	for _, n := range i {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return nil, fmt.Errorf("dynaGo:%s KeyFrom: nil %s on the key path", t.Name(), v.Type())
+			}
+			v = v.Elem()
+		}
+		v = v.Field(n)
+	}
+	return v.Interface(), nil
+}
+
+// Get reads the item with the key of i into i, which must be a pointer
+// to a struct.  If there is no such item, an *ItemNotFoundError is
+// returned and i is left as it is.
+func Get(svc dynamodbiface.DynamoDBAPI, i interface{}) error {
+	return GetWithContext(aws.BackgroundContext(), svc, i)
+}
+
+// GetWithContext is Get with a context and request options for the
+// request made.
+func GetWithContext(ctx aws.Context, svc dynamodbiface.DynamoDBAPI, i interface{}, opts ...request.Option) error {
+	if rv := reflect.ValueOf(i); rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return &InvalidDecodeError{reflect.TypeOf(i)}
+	}
+	k, err := KeyFrom(i)
+	if err != nil {
+		return err
+	}
+	resp, err := svc.GetItemWithContext(ctx, &dynamodb.GetItemInput{TableName: &k.tbln, Key: k.attr}, requestOptions(ctx, opts)...)
+	if err != nil {
+		return err
+	}
+	if len(resp.Item) == 0 {
+		return &ItemNotFoundError{k.tbln, k.attr}
+	}
+	return Unmarshal(resp.Item, i)
+}
+
 func GetItemInput(km KeyMaker, kv ...interface{}) (*dynamodb.GetItemInput, error) {
 	k, err := km(kv...)
 	if err != nil {
@@ -180,6 +302,9 @@ func getKeyAttributePath(t reflect.Type, kt string) []int {
 		if !opts.Contains(kt) {
 			continue
 		}
+		if f.Type == timeType {
+			return []int{n}
+		}
 		switch f.Type.Kind() {
 		case reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 			return []int{n}
@@ -192,48 +317,57 @@ func getKeyAttributePath(t reflect.Type, kt string) []int {
 	panic(&MissingKeyError{t, kt})
 }
 
-func getKeynameAndAttribute(t reflect.Type, i []int, k interface{}) (kn string, ka dynamodb.AttributeValue, err error) {
+func getKeynameAndAttribute(t reflect.Type, i []int, k interface{}, c Coercion) (kn string, ka dynamodb.AttributeValue, err error) {
 	//value from leaf
 	sf := t.FieldByIndex(i)
-	ka, err = createAttribute(sf, k)
+	ka, err = createAttribute(sf, k, c)
 	if err != nil {
 		return "", dynamodb.AttributeValue{}, err
 	}
-	//name from root
+	//name and prefix from root
 	rootkf := t.Field(i[0])
 	kn = getAttrName(rootkf)
+	if p := getPrefix(rootkf); p != "" {
+		ka = *addPrefix(p, sf.Type, &ka)
+	}
 	return
 }
 
 // checks to make sure the key value given matches the type
-// expected, and then returns a *dyanmodb.AttributeValue that
-// describes the field / value pair.
-func createAttribute(sf reflect.StructField, k interface{}) (ka dynamodb.AttributeValue, err error) {
-	switch sf.Type.Kind() {
-	case reflect.String:
-		s, ok := k.(string)
-		if !ok {
-			err = &KeyValueOfIncorrectType{reflect.String, reflect.TypeOf(k).Kind()}
-			return
-		}
-		ka = dynamodb.AttributeValue{S: &s}
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		v := reflect.ValueOf(k)
-		if !isInt(v) {
-			err = &KeyValueOfIncorrectType{reflect.Int, v.Kind()}
-			return
-		}
-		s := strconv.FormatInt(v.Int(), 10)
-		ka = dynamodb.AttributeValue{N: &s}
-	default:
-		panic(&UnsupportedKeyKindError{sf.Type.Kind()})
+// expected, coercing it as c allows, and then returns a
+// dynamodb.AttributeValue that describes the field / value pair.
+func createAttribute(sf reflect.StructField, k interface{}, c Coercion) (ka dynamodb.AttributeValue, err error) {
+	v := reflect.ValueOf(k)
+	var s string
+	ok := false
+	switch {
+	case sf.Type == timeType:
+		var n int64
+		n, ok = coerceTime(v, c)
+		s = strconv.FormatInt(n, 10)
+		ka.N = &s
+	case sf.Type.Kind() == reflect.String:
+		s, ok = coerceString(v, c)
+		ka.S = &s
+	case isIntKind(sf.Type.Kind()):
+		var n int64
+		n, ok = coerceInt(v, c)
+		s = strconv.FormatInt(n, 10)
+		ka.N = &s
+	}
+	if !ok {
+		return dynamodb.AttributeValue{}, &KeyValueOfIncorrectType{sf.Type.Kind(), v.Kind()}
 	}
 	return
 }
 
 // check if value is an int.. helper for AsGetItemInput
 func isInt(v reflect.Value) bool {
-	switch v.Kind() {
+	return isIntKind(v.Kind())
+}
+
+func isIntKind(k reflect.Kind) bool {
+	switch k {
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		return true
 	}
@@ -0,0 +1,67 @@
+// Copyright 2016 Appittome. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dynaGo
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/japhyf/dynaGo/dynagotest"
+)
+
+func TestKeyFrom(t *testing.T) {
+	at := time.Date(2016, 8, 1, 12, 0, 0, 0, time.UTC)
+	for _, c := range []struct {
+		v  interface{}
+		kv []interface{}
+	}{
+		{&Session{Usr: &Usr{Id: "u1"}, Id: "s1"}, []interface{}{"u1", "s1"}},
+		{Message{SessId: "s1", Timestamp: 42}, []interface{}{"s1", 42}},
+		{Post{Thread: "t1", At: at, Id: 7}, []interface{}{"t1", at, 7}},
+		{Wallet{Id: 1000, Owner: "bob"}, []interface{}{1000, "bob"}},
+	} {
+		k, err := KeyFrom(c.v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e, err := CreateKeyMaker(reflect.TypeOf(c.v))(c.kv...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(k, e) {
+			t.Errorf("KeyFrom(%v) = %v, expected %v", c.v, k.attr, e.attr)
+		}
+	}
+	if _, err := KeyFrom(Session{Id: "s1"}); err == nil {
+		t.Error("expected an error for a nil key struct")
+	}
+}
+
+func TestGetAndDeleteItem(t *testing.T) {
+	db := dynagotest.New()
+	if err := CreateTable(db, Wallet{}, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := Put(db, Wallet{Id: 1, Owner: "bob", Name: "checking"}); err != nil {
+		t.Fatal(err)
+	}
+	w := Wallet{Id: 1, Owner: "bob"}
+	if err := Get(db, &w); err != nil {
+		t.Fatal(err)
+	}
+	if w.Name != "checking" {
+		t.Errorf("unexpected wallet: %v", w)
+	}
+	if err := Get(db, w); err == nil {
+		t.Error("expected an error for a non-pointer")
+	}
+	if err := DeleteItem(db, w); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := Get(db, &w).(*ItemNotFoundError); !ok {
+		t.Error("expected the item to be deleted")
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright 2016 Appittome. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dynaGo
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// MigrationStep is a single UpdateTable call of a migration, either
+// creating or deleting one global secondary index.  dynamoDB accepts
+// only one index change per table at a time, so each step waits for the
+// previous one to finish.
+type MigrationStep struct {
+	TableName string
+	IndexName string
+	// the key schema of an index to create, nil for a deletion
+	KeySchema []*dynamodb.KeySchemaElement
+
+	update *dynamodb.UpdateTableInput
+}
+
+func (s MigrationStep) String() string {
+	if s.KeySchema == nil {
+		return s.TableName + ": delete index " + s.IndexName
+	}
+	return s.TableName + ": create index " + s.IndexName + " (" + keySchemaString(s.KeySchema) + ")"
+}
+
+// PlanMigration compares the tables of types to the schema derived from
+// their structs, and those of the types registered to the same tables,
+// and returns the index changes that would bring them in line.  Indexes are deleted before they are created, so a changed index
+// is replaced.  A table whose own key schema differs cannot be migrated
+// and is reported with a *SchemaDriftError.
+func PlanMigration(svc dynamodbiface.DynamoDBAPI, types ...interface{}) ([]MigrationStep, error) {
+	return PlanMigrationWithContext(aws.BackgroundContext(), svc, types)
+}
+
+// PlanMigrationWithContext is PlanMigration with a context and request
+// options for the requests made.
+func PlanMigrationWithContext(ctx aws.Context, svc dynamodbiface.DynamoDBAPI, types []interface{}, opts ...request.Option) ([]MigrationStep, error) {
+	steps := make([]MigrationStep, 0)
+	planned := make(map[string]bool)
+	for _, v := range types {
+		// types sharing a table share its plan
+		tn := TableName(reflectType(v))
+		if planned[tn] {
+			continue
+		}
+		planned[tn] = true
+		want, found, ds, err := describeDiff(ctx, svc, v, opts)
+		if err != nil {
+			return nil, err
+		}
+		var dels, adds []MigrationStep
+		for _, d := range ds {
+			switch d.Kind {
+			case DiffKeySchema:
+				return nil, &SchemaDriftError{tn, []SchemaDifference{d}}
+			case DiffGlobalSecondaryIndex:
+				if d.Found != "" {
+					dels = append(dels, deleteIndexStep(tn, d.Name))
+				}
+				if d.Want != "" {
+					adds = append(adds, createIndexStep(want, found, d.Name))
+				}
+			}
+		}
+		steps = append(append(steps, dels...), adds...)
+	}
+	return steps, nil
+}
+
+// Migrate applies the plan of PlanMigration one step at a time, waiting
+// for each table and its indexes to become ACTIVE again before moving on.
+func Migrate(svc dynamodbiface.DynamoDBAPI, types ...interface{}) error {
+	return MigrateWithContext(aws.BackgroundContext(), svc, types)
+}
+
+// MigrateWithContext is Migrate with a context for the requests made, and
+// for waiting on the tables, and request options for the requests.
+func MigrateWithContext(ctx aws.Context, svc dynamodbiface.DynamoDBAPI, types []interface{}, opts ...request.Option) error {
+	steps, err := PlanMigrationWithContext(ctx, svc, types, opts...)
+	if err != nil {
+		return err
+	}
+	for _, s := range steps {
+		if _, err := svc.UpdateTableWithContext(ctx, s.update, requestOptions(ctx, opts)...); err != nil {
+			return err
+		}
+		if err := WaitUntilTableActive(ctx, svc, s.TableName, nil, opts...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MigrateDryRun writes the plan of PlanMigration to w, one step per
+// line, without changing any table.
+func MigrateDryRun(svc dynamodbiface.DynamoDBAPI, w io.Writer, types ...interface{}) error {
+	return MigrateDryRunWithContext(aws.BackgroundContext(), svc, w, types)
+}
+
+// MigrateDryRunWithContext is MigrateDryRun with a context and request
+// options for the requests made.
+func MigrateDryRunWithContext(ctx aws.Context, svc dynamodbiface.DynamoDBAPI, w io.Writer, types []interface{}, opts ...request.Option) error {
+	steps, err := PlanMigrationWithContext(ctx, svc, types, opts...)
+	if err != nil {
+		return err
+	}
+	if len(steps) == 0 {
+		_, err = fmt.Fprintln(w, "dynaGo: no migration needed")
+		return err
+	}
+	for _, s := range steps {
+		if _, err := fmt.Fprintln(w, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func deleteIndexStep(tn, in string) MigrationStep {
+	return MigrationStep{
+		TableName: tn,
+		IndexName: in,
+		update: &dynamodb.UpdateTableInput{
+			TableName: aws.String(tn),
+			GlobalSecondaryIndexUpdates: []*dynamodb.GlobalSecondaryIndexUpdate{
+				{Delete: &dynamodb.DeleteGlobalSecondaryIndexAction{IndexName: aws.String(in)}},
+			},
+		},
+	}
+}
+
+// new indexes of a provisioned table get the capacity of the table
+func createIndexStep(want *dynamodb.CreateTableInput, found *dynamodb.TableDescription, in string) MigrationStep {
+	var gsi *dynamodb.GlobalSecondaryIndex
+	for _, g := range want.GlobalSecondaryIndexes {
+		if *g.IndexName == in {
+			gsi = g
+		}
+	}
+	create := &dynamodb.CreateGlobalSecondaryIndexAction{
+		IndexName:  gsi.IndexName,
+		KeySchema:  gsi.KeySchema,
+		Projection: gsi.Projection,
+	}
+	onDemand := found.BillingModeSummary != nil &&
+		aws.StringValue(found.BillingModeSummary.BillingMode) == dynamodb.BillingModePayPerRequest
+	if !onDemand && found.ProvisionedThroughput != nil {
+		create.ProvisionedThroughput = &dynamodb.ProvisionedThroughput{
+			ReadCapacityUnits:  found.ProvisionedThroughput.ReadCapacityUnits,
+			WriteCapacityUnits: found.ProvisionedThroughput.WriteCapacityUnits,
+		}
+	}
+	// only the keys of the new index need defining
+	ads := make([]*dynamodb.AttributeDefinition, 0, len(gsi.KeySchema))
+	for _, ad := range want.AttributeDefinitions {
+		for _, k := range gsi.KeySchema {
+			if *ad.AttributeName == *k.AttributeName {
+				ads = append(ads, ad)
+			}
+		}
+	}
+	return MigrationStep{
+		TableName: *want.TableName,
+		IndexName: in,
+		KeySchema: gsi.KeySchema,
+		update: &dynamodb.UpdateTableInput{
+			TableName:            want.TableName,
+			AttributeDefinitions: ads,
+			GlobalSecondaryIndexUpdates: []*dynamodb.GlobalSecondaryIndexUpdate{
+				{Create: create},
+			},
+		},
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2016 Appittome. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dynaGo
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/japhyf/dynaGo/dynagotest"
+)
+
+type Gadget struct {
+	Id    string `dynaGo:",HASH"`
+	Maker string `dynaGo:",gsi=ByMaker"`
+	Model string `dynaGo:",gsirange=ByMaker"`
+	Color string
+}
+
+// records the table calls made through it
+type tableRecorder struct {
+	*dynagotest.DB
+	calls []string
+}
+
+func (r *tableRecorder) UpdateTableWithContext(ctx aws.Context, in *dynamodb.UpdateTableInput, opts ...request.Option) (*dynamodb.UpdateTableOutput, error) {
+	r.calls = append(r.calls, "update")
+	return r.DB.UpdateTableWithContext(ctx, in, opts...)
+}
+
+func (r *tableRecorder) DescribeTableWithContext(ctx aws.Context, in *dynamodb.DescribeTableInput, opts ...request.Option) (*dynamodb.DescribeTableOutput, error) {
+	out, err := r.DB.DescribeTableWithContext(ctx, in, opts...)
+	if err == nil {
+		r.calls = append(r.calls, "describe "+*out.Table.TableStatus)
+	}
+	return out, err
+}
+
+// creates the table of Gadget as an older version of it declared it,
+// with a ByColor index in place of ByMaker
+func createOldGadgets(t *testing.T, db *dynagotest.DB) {
+	in := CreateTableInput(Gadget{}, TableOptions{ReadCapacity: 1, WriteCapacity: 1})
+	in.AttributeDefinitions = []*dynamodb.AttributeDefinition{
+		{AttributeName: aws.String("Id"), AttributeType: aws.String(dynamodb.ScalarAttributeTypeS)},
+		{AttributeName: aws.String("Color"), AttributeType: aws.String(dynamodb.ScalarAttributeTypeS)},
+	}
+	in.GlobalSecondaryIndexes = []*dynamodb.GlobalSecondaryIndex{{
+		IndexName:             aws.String("ByColor"),
+		KeySchema:             []*dynamodb.KeySchemaElement{{AttributeName: aws.String("Color"), KeyType: aws.String(dynamodb.KeyTypeHash)}},
+		Projection:            &dynamodb.Projection{ProjectionType: aws.String(dynamodb.ProjectionTypeAll)},
+		ProvisionedThroughput: in.ProvisionedThroughput,
+	}}
+	if _, err := db.CreateTable(in); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPlanMigration(t *testing.T) {
+	db := dynagotest.New()
+	createOldGadgets(t, db)
+	steps, err := PlanMigration(db, Gadget{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 2 || steps[0].KeySchema != nil || steps[0].IndexName != "ByColor" || steps[1].IndexName != "ByMaker" {
+		t.Fatalf("expected the deletion of ByColor before the creation of ByMaker, found %v", steps)
+	}
+	var b bytes.Buffer
+	if err := MigrateDryRun(db, &b, Gadget{}); err != nil {
+		t.Fatal(err)
+	}
+	expect := "Gadgets: delete index ByColor\nGadgets: create index ByMaker (Maker HASH, Model RANGE)\n"
+	if b.String() != expect {
+		t.Errorf("unexpected dry run:\n%s", b.String())
+	}
+
+	// a changed table key cannot be migrated
+	db = dynagotest.New()
+	in := CreateTableInput(Gadget{}, TableOptions{ReadCapacity: 1, WriteCapacity: 1})
+	in.KeySchema[0].AttributeName = aws.String("GadgetId")
+	in.AttributeDefinitions = append(in.AttributeDefinitions,
+		&dynamodb.AttributeDefinition{AttributeName: aws.String("GadgetId"), AttributeType: aws.String(dynamodb.ScalarAttributeTypeS)})
+	if _, err := db.CreateTable(in); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := PlanMigration(db, Gadget{}); err == nil {
+		t.Error("expected a SchemaDriftError")
+	} else if _, ok := err.(*SchemaDriftError); !ok {
+		t.Errorf("expected a SchemaDriftError, found %v", err)
+	}
+	if err := MigrateDryRun(db, &b, Gadget{}); err == nil {
+		t.Error("expected the dry run to report the drift")
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	defer func(o WaitOptions) { defaultWaitOptions = o }(defaultWaitOptions)
+	defaultWaitOptions.Delay = time.Millisecond
+
+	db := dynagotest.New()
+	createOldGadgets(t, db)
+	// the fake refuses an UpdateTable while the last one is in progress
+	db.StatusDelay = 2
+	r := &tableRecorder{DB: db}
+	if err := Migrate(r, Gadget{}); err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{
+		"update", "describe UPDATING", "describe UPDATING", "describe ACTIVE",
+		"update", "describe UPDATING", "describe UPDATING", "describe ACTIVE",
+	}
+	// the plan describes the table first
+	calls := r.calls[1:]
+	if len(calls) != len(expect) {
+		t.Fatalf("unexpected calls %v", r.calls)
+	}
+	for i := range expect {
+		if calls[i] != expect[i] {
+			t.Fatalf("unexpected calls %v", r.calls)
+		}
+	}
+
+	out, err := db.DescribeTable(&dynamodb.DescribeTableInput{TableName: aws.String("Gadgets")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gsis := out.Table.GlobalSecondaryIndexes
+	if len(gsis) != 1 || *gsis[0].IndexName != "ByMaker" || *gsis[0].IndexStatus != dynamodb.IndexStatusActive {
+		t.Errorf("unexpected indexes after the migration: %v", gsis)
+	}
+	var b bytes.Buffer
+	if err := MigrateDryRun(db, &b, Gadget{}); err != nil || b.String() != "dynaGo: no migration needed\n" {
+		t.Errorf("expected no further migration, found %q, %v", b.String(), err)
+	}
+}
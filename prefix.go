@@ -0,0 +1,90 @@
+// Copyright 2016 Appittome. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dynaGo
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// A field tagged "prefix" is stored with the prefix in front of its
+// value, as single table designs do to tell the keys of entity types
+// apart:
+//   Id string `dynaGo:"PK,HASH,prefix=USER#"`
+// Marshal writes "USER#1000" for the Id "1000", Unmarshal strips the
+// prefix again, and a KeyMaker adds it to the raw value it is given.
+// Prefixed attributes are strings, so a prefixed integer or time.Time
+// field is stored as type S, its number zero padded to the width of its
+// type as compose does, so that keys sort by value ("T#0000000000000000005"
+// for the int 5).  Negative numbers do not sort.  A prefix cannot contain
+// a comma.
+const tagPrefix = "prefix"
+
+// the prefix of sf, "" if it has none.  Panics if sf is of a kind that is
+// not stored as a string or a number.
+func getPrefix(sf reflect.StructField) string {
+	_, opts := parseTag(sf.Tag.Get("dynaGo"))
+	vs := opts.Values(tagPrefix)
+	if len(vs) == 0 {
+		return ""
+	}
+	switch sf.Type.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		panic(&TagOptionKindError{tagPrefix, sf.Type.Kind()})
+	}
+	return vs[0]
+}
+
+// av of a field of type t as a string with prefix p in front
+func addPrefix(p string, t reflect.Type, av *dynamodb.AttributeValue) *dynamodb.AttributeValue {
+	var s string
+	switch {
+	case av.S != nil:
+		s = p + *av.S
+	case av.N != nil:
+		s = p + padNumber(*av.N, t)
+	default:
+		return av
+	}
+	return &dynamodb.AttributeValue{S: &s}
+}
+
+// av without the prefix p, a number again if sf holds one
+func stripPrefix(p string, sf reflect.StructField, av *dynamodb.AttributeValue) *dynamodb.AttributeValue {
+	if av.S == nil {
+		return av
+	}
+	s := strings.TrimPrefix(*av.S, p)
+	t := sf.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType || isIntKind(t.Kind()) {
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			s = strconv.FormatInt(n, 10)
+		}
+		return &dynamodb.AttributeValue{N: &s}
+	}
+	return &dynamodb.AttributeValue{S: &s}
+}
+
+// the number n of an integer or time.Time field of type t, zero padded
+func padNumber(n string, t reflect.Type) string {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t != timeType && !isIntKind(t.Kind()) {
+		return n
+	}
+	i, err := strconv.ParseInt(n, 10, 64)
+	if err != nil {
+		return n
+	}
+	return fmt.Sprintf("%0*d", intWidth(t.Kind()), i)
+}
@@ -0,0 +1,95 @@
+// Copyright 2016 Appittome. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dynaGo
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/japhyf/dynaGo/dynagotest"
+)
+
+type Wallet struct {
+	Id    int    `dynaGo:"PK,HASH,prefix=WALLET#"`
+	Owner string `dynaGo:"SK,RANGE,prefix=USER#"`
+	Name  string
+}
+
+func TestPrefix(t *testing.T) {
+	in := Marshal(Wallet{Id: 1000, Owner: "bob", Name: "checking"})
+	if pk := in.Item["PK"]; pk == nil || pk.S == nil || *pk.S != "WALLET#0000000000000001000" {
+		t.Fatalf("unexpected prefixed attribute: %v", in.Item["PK"])
+	}
+	if sk := in.Item["SK"]; sk == nil || *sk.S != "USER#bob" {
+		t.Fatalf("unexpected prefixed attribute: %v", in.Item["SK"])
+	}
+
+	var a Wallet
+	if err := Unmarshal(in.Item, &a); err != nil {
+		t.Fatal(err)
+	}
+	if a.Id != 1000 || a.Owner != "bob" || a.Name != "checking" {
+		t.Errorf("unexpected decoded wallet: %v", a)
+	}
+
+	k, err := CreateKeyMaker(reflect.TypeOf(Wallet{}))(1000, "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *k.attr["PK"].S != "WALLET#0000000000000001000" || *k.attr["SK"].S != "USER#bob" {
+		t.Errorf("unexpected key: %v", k.attr)
+	}
+
+	ads := attributeDefinitionMap(CreateTableInput(Wallet{}, TableOptions{}).AttributeDefinitions)
+	if ads["PK"] != "S" || ads["SK"] != "S" {
+		t.Errorf("unexpected attribute types: %v", ads)
+	}
+}
+
+type Page struct {
+	Book string `dynaGo:",HASH"`
+	No   int16  `dynaGo:",RANGE,prefix=P#"`
+}
+
+func TestPrefixedNumbersSort(t *testing.T) {
+	db := dynagotest.New()
+	if err := CreateTable(db, Page{}, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	for _, no := range []int16{10, 5, 100, 9} {
+		if err := Put(db, Page{"b", no}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if sk := Marshal(Page{"b", 5}).Item["No"]; *sk.S != "P#00005" {
+		t.Errorf("unexpected prefixed number: %v", sk)
+	}
+	km := CreateKeyMaker(reflect.TypeOf(Page{}))
+	lo, _ := km("b", 9)
+	hi, _ := km("b", 100)
+	qi := &dynamodb.QueryInput{
+		TableName:                aws.String("Pages"),
+		KeyConditionExpression:   aws.String("Book = :b AND #n BETWEEN :lo AND :hi"),
+		ExpressionAttributeNames: map[string]*string{"#n": aws.String("No")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":b":  {S: aws.String("b")},
+			":lo": lo.attr["No"],
+			":hi": hi.attr["No"],
+		},
+	}
+	it := NewQueryIterator(aws.BackgroundContext(), db, qi, Page{})
+	var nos []int16
+	for it.Next() {
+		nos = append(nos, it.Item().(*Page).No)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(nos, []int16{9, 10, 100}) {
+		t.Errorf("expected pages 9 to 100 in order, found %v", nos)
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2016 Appittome. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dynaGo
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// ParallelScanOptions configures ParallelScanWithOptions.
+type ParallelScanOptions struct {
+	// the TotalSegments the table is split into
+	Segments int
+	// the most segments scanned at once, defaults to Segments
+	Workers int
+	// the Limit of each Scan request, 0 lets dynamoDB decide
+	PageSize int64
+	// a template for every request, eg. to add a FilterExpression.
+	// Its TableName defaults to the table of the scanned type.
+	Input *dynamodb.ScanInput
+}
+
+// ParallelScan reads the whole table of v, split into segments scanned
+// concurrently.  Every item is decoded into a new value of the type of
+// v and handed to fn as a pointer, items of other types registered to
+// the table are skipped.  A nil v decodes the items with
+// UnmarshalEntity, the table is then named by the Input of
+// ParallelScanWithOptions.  fn is called from several goroutines
+// at once, so it must be safe for concurrent use.
+//
+// The first error returned by fn stops the scan.  Errors are collected
+// per segment in a *ParallelScanError.  opts apply to every Scan
+// request.
+func ParallelScan(ctx aws.Context, svc dynamodbiface.DynamoDBAPI, v interface{}, segments int, fn func(interface{}) error, opts ...request.Option) error {
+	return ParallelScanWithOptions(ctx, svc, v, ParallelScanOptions{Segments: segments}, fn, opts...)
+}
+
+// ParallelScanWithOptions is ParallelScan configured by o.
+func ParallelScanWithOptions(ctx aws.Context, svc dynamodbiface.DynamoDBAPI, v interface{}, o ParallelScanOptions, fn func(interface{}) error, opts ...request.Option) error {
+	if o.Segments < 1 {
+		o.Segments = 1
+	}
+	if o.Workers < 1 || o.Workers > o.Segments {
+		o.Workers = o.Segments
+	}
+	base := dynamodb.ScanInput{}
+	if o.Input != nil {
+		base = *o.Input
+	}
+	if base.TableName == nil && v != nil {
+		base.TableName = aws.String(TableName(reflectType(v)))
+	}
+	base.TotalSegments = aws.Int64(int64(o.Segments))
+	if o.PageSize > 0 {
+		base.Limit = aws.Int64(o.PageSize)
+	}
+
+	sctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		mu   sync.Mutex
+		errs = make(map[int]error)
+		wg   sync.WaitGroup
+	)
+	// errors following a cancellation are a consequence, not a cause
+	fail := func(seg int, err error) {
+		mu.Lock()
+		if sctx.Err() == nil {
+			errs[seg] = err
+		}
+		mu.Unlock()
+		cancel()
+	}
+
+	segs := make(chan int)
+	for w := 0; w < o.Workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for seg := range segs {
+				in := base
+				in.Segment = aws.Int64(int64(seg))
+				it := NewScanIterator(sctx, svc, &in, v, opts...)
+				for it.Next() {
+					if err := fn(it.Item()); err != nil {
+						fail(seg, err)
+						break
+					}
+				}
+				if err := it.Err(); err != nil {
+					fail(seg, err)
+				}
+			}
+		}()
+	}
+feed:
+	for seg := 0; seg < o.Segments; seg++ {
+		select {
+		case segs <- seg:
+		case <-sctx.Done():
+			break feed
+		}
+	}
+	close(segs)
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return &ParallelScanError{errs}
+	}
+	return ctx.Err()
+}
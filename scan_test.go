@@ -0,0 +1,134 @@
+// Copyright 2016 Appittome. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dynaGo
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/japhyf/dynaGo/dynagotest"
+)
+
+type Reading struct {
+	Id    string `dynaGo:",HASH"`
+	Value int64
+}
+
+// records the Scan requests made through it, each taking a moment so
+// that concurrent requests overlap
+type scanRecorder struct {
+	*dynagotest.DB
+	mu       sync.Mutex
+	inFlight int
+	most     int
+	segments map[int64]int
+}
+
+func (r *scanRecorder) ScanWithContext(ctx aws.Context, in *dynamodb.ScanInput, opts ...request.Option) (*dynamodb.ScanOutput, error) {
+	r.mu.Lock()
+	r.inFlight++
+	if r.inFlight > r.most {
+		r.most = r.inFlight
+	}
+	r.segments[aws.Int64Value(in.Segment)]++
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		r.inFlight--
+		r.mu.Unlock()
+	}()
+	time.Sleep(time.Millisecond)
+	return r.DB.ScanWithContext(ctx, in, opts...)
+}
+
+func newReadings(t *testing.T, n int) *scanRecorder {
+	db := dynagotest.New()
+	if err := CreateTable(db, Reading{}, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		if err := Put(db, Reading{Id: "r" + strconv.Itoa(i), Value: int64(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &scanRecorder{DB: db, segments: make(map[int64]int)}
+}
+
+func TestParallelScan(t *testing.T) {
+	r := newReadings(t, 40)
+	var mu sync.Mutex
+	seen := make(map[string]int)
+	err := ParallelScanWithOptions(aws.BackgroundContext(), r, Reading{}, ParallelScanOptions{Segments: 6, Workers: 2, PageSize: 4},
+		func(v interface{}) error {
+			mu.Lock()
+			seen[v.(*Reading).Id]++
+			mu.Unlock()
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != 40 {
+		t.Errorf("expected 40 readings, found %d", len(seen))
+	}
+	for id, n := range seen {
+		if n != 1 {
+			t.Errorf("%s was handed to fn %d times", id, n)
+		}
+	}
+	if len(r.segments) != 6 {
+		t.Errorf("expected 6 segments to be scanned, found %v", r.segments)
+	}
+	if r.most > 2 {
+		t.Errorf("expected at most 2 concurrent scans, found %d", r.most)
+	}
+}
+
+func TestParallelScanStops(t *testing.T) {
+	r := newReadings(t, 40)
+	stop := errors.New("stop")
+	var mu sync.Mutex
+	calls := 0
+	err := ParallelScanWithOptions(aws.BackgroundContext(), r, Reading{}, ParallelScanOptions{Segments: 4, PageSize: 1},
+		func(v interface{}) error {
+			mu.Lock()
+			defer mu.Unlock()
+			calls++
+			if v.(*Reading).Id == "r1" {
+				return stop
+			}
+			return nil
+		})
+	pse, ok := err.(*ParallelScanError)
+	if !ok {
+		t.Fatalf("expected a ParallelScanError, found %v", err)
+	}
+	// the other segments are canceled, their errors are not reported
+	if len(pse.Errors) != 1 {
+		t.Fatalf("expected the error of one segment, found %v", pse.Errors)
+	}
+	for _, e := range pse.Errors {
+		if e != stop {
+			t.Errorf("expected the error of fn, found %v", e)
+		}
+	}
+	if calls >= 40 {
+		t.Errorf("expected the scan to stop early, fn was called %d times", calls)
+	}
+
+	ctx, cancel := context.WithCancel(aws.BackgroundContext())
+	cancel()
+	err = ParallelScan(ctx, r, Reading{}, 4, func(interface{}) error { return nil })
+	if err != context.Canceled {
+		t.Errorf("expected the context error, found %v", err)
+	}
+}
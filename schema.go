@@ -0,0 +1,164 @@
+// Copyright 2016 Appittome. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dynaGo
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// The kinds of SchemaDifference
+const (
+	DiffKeySchema            = "KeySchema"
+	DiffAttributeDefinition  = "AttributeDefinition"
+	DiffGlobalSecondaryIndex = "GlobalSecondaryIndex"
+)
+
+// SchemaDifference is one way in which a live table disagrees with the
+// schema derived from its struct.  Name is an attribute name, or an
+// index name for DiffGlobalSecondaryIndex.  Want is derived from the
+// struct and Found described by dynamoDB, either is "" when absent:
+//   - KeySchema: the key type, HASH or RANGE
+//   - AttributeDefinition: the scalar type, S, N or B
+//   - GlobalSecondaryIndex: the index key schema, eg. "Origin HASH"
+type SchemaDifference struct {
+	Kind  string
+	Name  string
+	Want  string
+	Found string
+}
+
+func (d SchemaDifference) String() string {
+	return d.Kind + " " + d.Name + ": want \"" + d.Want + "\" found \"" + d.Found + "\""
+}
+
+// EnsureTable creates the table of v as CreateTableWithOptions does,
+// unless the table already exists.  An existing table is compared to
+// the schema derived from v, and a *SchemaDriftError describing every
+// difference is returned if they disagree.  If v has a "ttl" field,
+// time to live is enabled on an existing table that lacks it.
+func EnsureTable(svc dynamodbiface.DynamoDBAPI, v interface{}, o TableOptions) error {
+	return EnsureTableWithContext(aws.BackgroundContext(), svc, v, o)
+}
+
+// EnsureTableWithContext is EnsureTable with a context for the requests
+// made, and for waiting on a new table, and request options for the
+// requests.
+func EnsureTableWithContext(ctx aws.Context, svc dynamodbiface.DynamoDBAPI, v interface{}, o TableOptions, opts ...request.Option) error {
+	ds, err := DiffTableWithContext(ctx, svc, v, opts...)
+	switch {
+	case isErrCode(err, dynamodb.ErrCodeResourceNotFoundException):
+		return CreateTableWithContext(ctx, svc, v, o, opts...)
+	case err != nil:
+		return err
+	case len(ds) > 0:
+		return &SchemaDriftError{TableName(reflectType(v)), ds}
+	}
+	if _, ok := getTTLField(reflectType(v)); !ok {
+		return nil
+	}
+	tn := TableName(reflectType(v))
+	resp, err := svc.DescribeTimeToLiveWithContext(ctx, &dynamodb.DescribeTimeToLiveInput{TableName: &tn}, requestOptions(ctx, opts)...)
+	if err != nil {
+		return err
+	}
+	switch aws.StringValue(resp.TimeToLiveDescription.TimeToLiveStatus) {
+	case dynamodb.TimeToLiveStatusEnabled, dynamodb.TimeToLiveStatusEnabling:
+		return nil
+	}
+	return EnableTimeToLiveWithContext(ctx, svc, v, opts...)
+}
+
+// DiffTable describes the table of v and returns the differences
+// between it and the schema CreateTable would create.  A missing table
+// is reported by the ResourceNotFoundException of DescribeTable.
+func DiffTable(svc dynamodbiface.DynamoDBAPI, v interface{}) ([]SchemaDifference, error) {
+	return DiffTableWithContext(aws.BackgroundContext(), svc, v)
+}
+
+// DiffTableWithContext is DiffTable with a context and request options
+// for the request made.
+func DiffTableWithContext(ctx aws.Context, svc dynamodbiface.DynamoDBAPI, v interface{}, opts ...request.Option) ([]SchemaDifference, error) {
+	_, _, ds, err := describeDiff(ctx, svc, v, opts)
+	return ds, err
+}
+
+// the derived and described schema of the table of v, and how they differ
+func describeDiff(ctx aws.Context, svc dynamodbiface.DynamoDBAPI, v interface{}, opts []request.Option) (*dynamodb.CreateTableInput, *dynamodb.TableDescription, []SchemaDifference, error) {
+	want := CreateTableInput(v, TableOptions{})
+	resp, err := svc.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{TableName: want.TableName}, requestOptions(ctx, opts)...)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return want, resp.Table, diffSchema(want, resp.Table), nil
+}
+
+func diffSchema(want *dynamodb.CreateTableInput, found *dynamodb.TableDescription) []SchemaDifference {
+	ds := diffMaps(DiffKeySchema, keySchemaMap(want.KeySchema), keySchemaMap(found.KeySchema))
+	ds = append(ds, diffMaps(DiffAttributeDefinition,
+		attributeDefinitionMap(want.AttributeDefinitions),
+		attributeDefinitionMap(found.AttributeDefinitions))...)
+
+	wi := make(map[string]string)
+	for _, gsi := range want.GlobalSecondaryIndexes {
+		wi[*gsi.IndexName] = keySchemaString(gsi.KeySchema)
+	}
+	fi := make(map[string]string)
+	for _, gsi := range found.GlobalSecondaryIndexes {
+		fi[*gsi.IndexName] = keySchemaString(gsi.KeySchema)
+	}
+	return append(ds, diffMaps(DiffGlobalSecondaryIndex, wi, fi)...)
+}
+
+// compares want and found by key, in key order
+func diffMaps(kind string, want, found map[string]string) []SchemaDifference {
+	ns := make([]string, 0, len(want)+len(found))
+	for n := range want {
+		ns = append(ns, n)
+	}
+	for n := range found {
+		if _, ok := want[n]; !ok {
+			ns = append(ns, n)
+		}
+	}
+	sort.Strings(ns)
+	ds := make([]SchemaDifference, 0)
+	for _, n := range ns {
+		if want[n] != found[n] {
+			ds = append(ds, SchemaDifference{kind, n, want[n], found[n]})
+		}
+	}
+	return ds
+}
+
+func keySchemaMap(ks []*dynamodb.KeySchemaElement) map[string]string {
+	m := make(map[string]string)
+	for _, k := range ks {
+		m[*k.AttributeName] = *k.KeyType
+	}
+	return m
+}
+
+func attributeDefinitionMap(ads []*dynamodb.AttributeDefinition) map[string]string {
+	m := make(map[string]string)
+	for _, ad := range ads {
+		m[*ad.AttributeName] = *ad.AttributeType
+	}
+	return m
+}
+
+// eg. "Origin HASH, Alias RANGE"
+func keySchemaString(ks []*dynamodb.KeySchemaElement) string {
+	ss := make([]string, 0, len(ks))
+	for _, k := range ks {
+		ss = append(ss, *k.AttributeName+" "+*k.KeyType)
+	}
+	return strings.Join(ss, ", ")
+}
@@ -0,0 +1,282 @@
+// Copyright 2016 Appittome. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dynaGo
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// TableOptions describes everything about a table that cannot be read
+// from the struct it stores.  The KeySchema and AttributeDefinitions
+// are always derived from the struct tags.
+type TableOptions struct {
+	// dynamodb.BillingModeProvisioned (the default when empty) or
+	// dynamodb.BillingModePayPerRequest.  The capacities are only used
+	// for provisioned tables, a zero capacity is 1 unit.
+	BillingMode   string
+	ReadCapacity  int64
+	WriteCapacity int64
+
+	// enables a stream of the given dynamodb.StreamViewType when set
+	StreamViewType string
+
+	// server side encryption, the table default is used when nil
+	SSE *dynamodb.SSESpecification
+
+	// dynamodb.TableClassStandard or dynamodb.TableClassStandardInfrequentAccess
+	TableClass string
+
+	Tags               map[string]string
+	DeletionProtection bool
+
+	// when set, creation waits until the table and its indexes are
+	// ACTIVE, polling as described by the WaitOptions
+	Wait *WaitOptions
+}
+
+// indexes of provisioned tables are given the capacity of the table.
+// dynamoDB refuses a capacity below 1, the least is used for zero.
+func (o TableOptions) provisionedThroughput() *dynamodb.ProvisionedThroughput {
+	r, w := o.ReadCapacity, o.WriteCapacity
+	if r == 0 {
+		r = 1
+	}
+	if w == 0 {
+		w = 1
+	}
+	return &dynamodb.ProvisionedThroughput{
+		ReadCapacityUnits:  aws.Int64(r),
+		WriteCapacityUnits: aws.Int64(w),
+	}
+}
+
+// Try to create a table if it doesn't already exist
+// If it does exist or cannot be created, return error
+//   - Tables are created from structs only, and will panic on any other type
+//   - Table name will be [structName] + s (ie type Doc struct {...} => table "Docs")
+//   - The table is provisioned with w write and r read capacity units,
+//     see CreateTableWithOptions for other settings
+//   - If a field is tagged "ttl", it waits up to five minutes for the
+//     table to become ACTIVE to enable time to live
+func CreateTable(svc dynamodbiface.DynamoDBAPI, v interface{}, w int64, r int64) error {
+	return CreateTableWithOptions(svc, v, TableOptions{ReadCapacity: r, WriteCapacity: w})
+}
+
+// CreateTableWithOptions is CreateTable with the table settings given by
+// o.  If a field is tagged "ttl", it waits for the new table and enables
+// time to live on that attribute.  Without o.Wait, the wait times out
+// after five minutes.
+func CreateTableWithOptions(svc dynamodbiface.DynamoDBAPI, v interface{}, o TableOptions) error {
+	return CreateTableWithContext(aws.BackgroundContext(), svc, v, o)
+}
+
+// CreateTableWithContext is CreateTableWithOptions with a context and
+// request options for the requests made.  The context also bounds the
+// wait for the table to become ACTIVE.
+func CreateTableWithContext(ctx aws.Context, svc dynamodbiface.DynamoDBAPI, v interface{}, o TableOptions, opts ...request.Option) error {
+	params := CreateTableInput(v, o)
+	if err := tableExists(ctx, svc, *params.TableName, opts); err != nil {
+		return err
+	}
+	if _, err := svc.CreateTableWithContext(ctx, params, requestOptions(ctx, opts)...); err != nil {
+		return err
+	}
+	forgetTables(svc)
+	_, ttl := getTTLField(reflectType(v))
+	w := o.Wait
+	if w == nil {
+		if !ttl {
+			return nil
+		}
+		w = &WaitOptions{Timeout: ttlWaitTimeout}
+	}
+	if err := WaitUntilTableActive(ctx, svc, *params.TableName, w, opts...); err != nil {
+		return err
+	}
+	if !ttl {
+		return nil
+	}
+	return EnableTimeToLiveWithContext(ctx, svc, v, opts...)
+}
+
+// CreateTableInput returns the dynamodb.CreateTableInput CreateTable
+// would send for v, for callers that need to adjust it further.  The
+// table of a registered type is given the attributes and indexes of
+// every type registered to it.
+func CreateTableInput(v interface{}, o TableOptions) *dynamodb.CreateTableInput {
+	params := tableSchema(reflectType(v))
+	if o.BillingMode == dynamodb.BillingModePayPerRequest {
+		params.BillingMode = aws.String(o.BillingMode)
+	} else {
+		params.ProvisionedThroughput = o.provisionedThroughput()
+		for _, gsi := range params.GlobalSecondaryIndexes {
+			gsi.ProvisionedThroughput = o.provisionedThroughput()
+		}
+	}
+	if o.StreamViewType != "" {
+		params.StreamSpecification = &dynamodb.StreamSpecification{
+			StreamEnabled:  aws.Bool(true),
+			StreamViewType: aws.String(o.StreamViewType),
+		}
+	}
+	params.SSESpecification = o.SSE
+	if o.TableClass != "" {
+		params.TableClass = aws.String(o.TableClass)
+	}
+	if o.DeletionProtection {
+		params.DeletionProtectionEnabled = aws.Bool(true)
+	}
+	// sorted, so the input is the same on every call
+	ks := make([]string, 0, len(o.Tags))
+	for k := range o.Tags {
+		ks = append(ks, k)
+	}
+	sort.Strings(ks)
+	for _, k := range ks {
+		params.Tags = append(params.Tags, &dynamodb.Tag{Key: aws.String(k), Value: aws.String(o.Tags[k])})
+	}
+	return params
+}
+
+// the key schema, attribute definitions and indexes of t alone
+func typeSchema(t reflect.Type) *dynamodb.CreateTableInput {
+	tn := TableName(t)
+	e := newTableEncoderState()
+	encode(e, reflect.Zero(t).Interface())
+	params := &dynamodb.CreateTableInput{
+		TableName:            &tn,
+		KeySchema:            e.keySchema,
+		AttributeDefinitions: e.attributeDefinitions,
+	}
+	if gsis := e.globalSecondaryIndexes(t); len(gsis) > 0 {
+		params.GlobalSecondaryIndexes = gsis
+	}
+	return params
+}
+
+// the schema of the table of t, the union of the schemas of the types
+// stored there.  The key schema is shared, the first definition of an
+// attribute or an index is kept, and the indexes are sorted by name.
+func tableSchema(t reflect.Type) *dynamodb.CreateTableInput {
+	ts := tableTypes(t)
+	params := typeSchema(ts[0])
+	ads := attributeDefinitionMap(params.AttributeDefinitions)
+	gsis := make(map[string]bool)
+	for _, gsi := range params.GlobalSecondaryIndexes {
+		gsis[*gsi.IndexName] = true
+	}
+	for _, t := range ts[1:] {
+		s := typeSchema(t)
+		for _, ad := range s.AttributeDefinitions {
+			if _, ok := ads[*ad.AttributeName]; !ok {
+				ads[*ad.AttributeName] = *ad.AttributeType
+				params.AttributeDefinitions = append(params.AttributeDefinitions, ad)
+			}
+		}
+		for _, gsi := range s.GlobalSecondaryIndexes {
+			if !gsis[*gsi.IndexName] {
+				gsis[*gsi.IndexName] = true
+				params.GlobalSecondaryIndexes = append(params.GlobalSecondaryIndexes, gsi)
+			}
+		}
+	}
+	sort.Slice(params.GlobalSecondaryIndexes, func(i, j int) bool {
+		return *params.GlobalSecondaryIndexes[i].IndexName < *params.GlobalSecondaryIndexes[j].IndexName
+	})
+	return params
+}
+
+// how long CreateTable waits for a table with a ttl field to become
+// ACTIVE, when no WaitOptions are given
+var ttlWaitTimeout = 5 * time.Minute
+
+// TableCacheTTL is how long the table names listed by CreateTable are
+// remembered for each client.  Zero, the default, lists them every time.
+// Creating or deleting a table through dynaGo clears the cache of that
+// client.  Clients are told apart by pointer, clients that are not
+// pointers are never cached.
+var TableCacheTTL time.Duration
+
+type tableList struct {
+	names   map[string]bool
+	expires time.Time
+}
+
+var tableCache = struct {
+	sync.Mutex
+	lists map[dynamodbiface.DynamoDBAPI]tableList
+}{lists: make(map[dynamodbiface.DynamoDBAPI]tableList)}
+
+func tableExists(ctx aws.Context, svc dynamodbiface.DynamoDBAPI, tn string, opts []request.Option) error {
+	names, err := listTables(ctx, svc, opts)
+	if err != nil {
+		return err
+	}
+	if names[tn] {
+		return TableExistsError{tn}
+	}
+	return nil
+}
+
+// the names of all tables, following LastEvaluatedTableName through
+// every page of ListTables.
+func listTables(ctx aws.Context, svc dynamodbiface.DynamoDBAPI, opts []request.Option) (map[string]bool, error) {
+	ttl := TableCacheTTL
+	if !cacheable(svc) {
+		ttl = 0
+	}
+	if ttl > 0 {
+		tableCache.Lock()
+		l, ok := tableCache.lists[svc]
+		if ok && !time.Now().Before(l.expires) {
+			delete(tableCache.lists, svc)
+			ok = false
+		}
+		tableCache.Unlock()
+		if ok {
+			return l.names, nil
+		}
+	}
+	names := make(map[string]bool)
+	err := svc.ListTablesPagesWithContext(ctx, &dynamodb.ListTablesInput{},
+		func(page *dynamodb.ListTablesOutput, last bool) bool {
+			for _, n := range page.TableNames {
+				names[*n] = true
+			}
+			return true
+		}, requestOptions(ctx, opts)...)
+	if err != nil {
+		return nil, err
+	}
+	if ttl > 0 {
+		tableCache.Lock()
+		tableCache.lists[svc] = tableList{names, time.Now().Add(ttl)}
+		tableCache.Unlock()
+	}
+	return names, nil
+}
+
+func forgetTables(svc dynamodbiface.DynamoDBAPI) {
+	if !cacheable(svc) {
+		return
+	}
+	tableCache.Lock()
+	delete(tableCache.lists, svc)
+	tableCache.Unlock()
+}
+
+// whether svc can key tableCache.  Only pointers are, other comparable
+// types may still hold uncomparable values, which panic as a map key.
+func cacheable(svc dynamodbiface.DynamoDBAPI) bool {
+	return reflect.TypeOf(svc).Kind() == reflect.Ptr
+}
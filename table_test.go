@@ -0,0 +1,225 @@
+// Copyright 2016 Appittome. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dynaGo
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/japhyf/dynaGo/dynagotest"
+)
+
+func TestCreateTableInput(t *testing.T) {
+	in := CreateTableInput(Message{}, TableOptions{
+		BillingMode:    dynamodb.BillingModePayPerRequest,
+		StreamViewType: dynamodb.StreamViewTypeNewImage,
+		Tags:           map[string]string{"team": "chat", "env": "test"},
+	})
+	if *in.TableName != "Messages" || len(in.KeySchema) != 2 {
+		t.Errorf("unexpected schema: %v", in)
+	}
+	if in.ProvisionedThroughput != nil || *in.BillingMode != dynamodb.BillingModePayPerRequest {
+		t.Errorf("expected on-demand billing: %v", in)
+	}
+	if !*in.StreamSpecification.StreamEnabled {
+		t.Errorf("expected stream to be enabled")
+	}
+	if len(in.Tags) != 2 || *in.Tags[0].Key != "env" {
+		t.Errorf("unexpected tags: %v", in.Tags)
+	}
+
+	in = CreateTableInput(Message{}, TableOptions{ReadCapacity: 1, WriteCapacity: 2})
+	if in.BillingMode != nil || *in.ProvisionedThroughput.WriteCapacityUnits != 2 {
+		t.Errorf("expected provisioned billing: %v", in)
+	}
+
+	// zero capacities are the least dynamoDB accepts, on indexes as well
+	in = CreateTableInput(Member{}, TableOptions{})
+	pts := []*dynamodb.ProvisionedThroughput{in.ProvisionedThroughput}
+	for _, gsi := range in.GlobalSecondaryIndexes {
+		pts = append(pts, gsi.ProvisionedThroughput)
+	}
+	if len(pts) != 3 {
+		t.Fatalf("expected 2 indexes: %v", in.GlobalSecondaryIndexes)
+	}
+	for _, pt := range pts {
+		if *pt.ReadCapacityUnits != 1 || *pt.WriteCapacityUnits != 1 {
+			t.Errorf("expected a capacity of 1/1, found %v", pt)
+		}
+	}
+}
+
+type Member struct {
+	Id     string `dynaGo:"MemberId,HASH"`
+	Origin string `dynaGo:",gsi=ByOrigin,gsi=ByOriginAlias"`
+	Alias  string `dynaGo:",gsirange=ByOriginAlias"`
+	Email  string
+}
+
+func TestDiffSchema(t *testing.T) {
+	want := CreateTableInput(Member{}, TableOptions{})
+	if len(want.GlobalSecondaryIndexes) != 2 || len(want.AttributeDefinitions) != 3 {
+		t.Fatalf("unexpected indexes: %v", want)
+	}
+	found := &dynamodb.TableDescription{
+		KeySchema:            want.KeySchema,
+		AttributeDefinitions: want.AttributeDefinitions[:2],
+		GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndexDescription{
+			{IndexName: want.GlobalSecondaryIndexes[0].IndexName, KeySchema: want.KeySchema},
+		},
+	}
+	ds := diffSchema(want, found)
+	expect := []SchemaDifference{
+		{DiffAttributeDefinition, "Alias", "S", ""},
+		{DiffGlobalSecondaryIndex, "ByOrigin", "Origin HASH", "MemberId HASH"},
+		{DiffGlobalSecondaryIndex, "ByOriginAlias", "Origin HASH, Alias RANGE", ""},
+	}
+	if !reflect.DeepEqual(ds, expect) {
+		t.Errorf("unexpected differences:\n\t%v\n\t%v", ds, expect)
+	}
+}
+
+// creates n tables the names of which sort before those of the tests
+func createFillerTables(t *testing.T, db *dynagotest.DB, n int) {
+	for i := 0; i < n; i++ {
+		_, err := db.CreateTable(&dynamodb.CreateTableInput{
+			TableName: aws.String(fmt.Sprintf("Aaa%03d", i)),
+			KeySchema: []*dynamodb.KeySchemaElement{{AttributeName: aws.String("Id"), KeyType: aws.String(dynamodb.KeyTypeHash)}},
+			AttributeDefinitions: []*dynamodb.AttributeDefinition{
+				{AttributeName: aws.String("Id"), AttributeType: aws.String(dynamodb.ScalarAttributeTypeS)},
+			},
+			ProvisionedThroughput: &dynamodb.ProvisionedThroughput{ReadCapacityUnits: aws.Int64(1), WriteCapacityUnits: aws.Int64(1)},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestTableExistsOnLaterPage(t *testing.T) {
+	db := dynagotest.New()
+	createFillerTables(t, db, 120)
+	if err := CreateTable(db, Message{}, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	// Messages is listed on the second page of ListTables
+	if err := CreateTable(db, Message{}, 1, 1); err != (TableExistsError{"Messages"}) {
+		t.Errorf("expected a TableExistsError, found %v", err)
+	}
+}
+
+// counts the ListTables pages requested through it
+type listRecorder struct {
+	*dynagotest.DB
+	lists int
+}
+
+func (r *listRecorder) ListTablesPagesWithContext(ctx aws.Context, in *dynamodb.ListTablesInput, fn func(*dynamodb.ListTablesOutput, bool) bool, opts ...request.Option) error {
+	r.lists++
+	return r.DB.ListTablesPagesWithContext(ctx, in, fn, opts...)
+}
+
+func TestTableCache(t *testing.T) {
+	defer func(ttl time.Duration) { TableCacheTTL = ttl }(TableCacheTTL)
+	TableCacheTTL = time.Hour
+	r := &listRecorder{DB: dynagotest.New()}
+	ctx := aws.BackgroundContext()
+	for i := 0; i < 2; i++ {
+		if err := tableExists(ctx, r, "Messages", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if r.lists != 1 {
+		t.Errorf("expected the second lookup to be cached, found %d lists", r.lists)
+	}
+
+	// creating the table, looked up in the cache, clears it
+	if err := CreateTable(r, Message{}, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := tableExists(ctx, r, "Messages", nil); err == nil {
+		t.Error("expected the new table to be found")
+	}
+	if r.lists != 2 {
+		t.Errorf("expected the cache to be cleared, found %d lists", r.lists)
+	}
+
+	// tables created elsewhere are not seen until the cache expires
+	createFillerTables(t, r.DB, 1)
+	if err := tableExists(ctx, r, "Aaa000", nil); err != nil {
+		t.Errorf("expected the cached list, found %v", err)
+	}
+	TableCacheTTL = time.Millisecond
+	forgetTables(r)
+	tableExists(ctx, r, "Aaa000", nil)
+	time.Sleep(2 * time.Millisecond)
+	if err := tableExists(ctx, r, "Aaa000", nil); err == nil {
+		t.Error("expected the expired list to be listed again")
+	}
+	if r.lists != 4 {
+		t.Errorf("expected 4 lists, found %d", r.lists)
+	}
+}
+
+// a client passed by value, whose comparable type holds an uncomparable
+// value
+type valueClient struct {
+	dynamodbiface.DynamoDBAPI
+}
+
+type uncomparable struct {
+	*dynagotest.DB
+	tags []string
+}
+
+func TestTableCacheSkipsValues(t *testing.T) {
+	defer func(ttl time.Duration) { TableCacheTTL = ttl }(TableCacheTTL)
+	TableCacheTTL = time.Hour
+	svc := valueClient{uncomparable{DB: dynagotest.New()}}
+	ctx := aws.BackgroundContext()
+	if err := tableExists(ctx, svc, "Messages", nil); err != nil {
+		t.Fatal(err)
+	}
+	// keying the cache by svc would panic
+	if err := CreateTable(svc, Message{}, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := tableExists(ctx, svc, "Messages", nil); err == nil {
+		t.Error("expected the new table to be found")
+	}
+}
+
+type listFailer struct {
+	*dynagotest.DB
+}
+
+func (f *listFailer) ListTablesPagesWithContext(aws.Context, *dynamodb.ListTablesInput, func(*dynamodb.ListTablesOutput, bool) bool, ...request.Option) error {
+	return errors.New("no tables")
+}
+
+func TestTableCacheEvictsExpired(t *testing.T) {
+	defer func(ttl time.Duration) { TableCacheTTL = ttl }(TableCacheTTL)
+	TableCacheTTL = time.Hour
+	f := &listFailer{dynagotest.New()}
+	tableCache.Lock()
+	tableCache.lists[f] = tableList{map[string]bool{"Messages": true}, time.Now().Add(-time.Second)}
+	tableCache.Unlock()
+	if err := tableExists(aws.BackgroundContext(), f, "Messages", nil); err == nil || err.Error() != "no tables" {
+		t.Errorf("expected the expired list to be listed again, found %v", err)
+	}
+	tableCache.Lock()
+	_, ok := tableCache.lists[f]
+	tableCache.Unlock()
+	if ok {
+		t.Error("expected the expired list to be evicted")
+	}
+}
@@ -29,3 +29,14 @@ func TestTagParsing(t *testing.T) {
 		}
 	}
 }
+
+func TestTagValues(t *testing.T) {
+	_, opts := parseTag(",gsi=ByOrigin,HASH,gsi=ByEmail,gsirange=ByAlias")
+	vs := opts.Values("gsi")
+	if len(vs) != 2 || vs[0] != "ByOrigin" || vs[1] != "ByEmail" {
+		t.Errorf("Values(%q) = %v", "gsi", vs)
+	}
+	if vs := opts.Values("HASH"); len(vs) != 0 {
+		t.Errorf("Values(%q) = %v", "HASH", vs)
+	}
+}
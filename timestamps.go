@@ -0,0 +1,73 @@
+// Copyright 2016 Appittome. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dynaGo
+
+import (
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// Integer or time.Time fields tagged "createdAt" or "updatedAt" are
+// maintained by dynaGo as unix timestamps (seconds):
+//   Created int64     `dynaGo:",createdAt"`
+//   Updated time.Time `dynaGo:",updatedAt"`
+// updatedAt is set on every write.  createdAt is set by Marshal when the
+// field is still zero, and by MarshalUpdate only if the stored item does
+// not have one yet (if_not_exists).  Only Update (MarshalUpdate) keeps
+// the createdAt of a stored item: Put replaces the whole item, so a Put
+// of a struct whose createdAt field is zero stamps it anew.  Read the
+// item first, or use Update, to keep the time of the first write.
+const (
+	tagCreatedAt = "createdAt"
+	tagUpdatedAt = "updatedAt"
+)
+
+// Now is the clock used for createdAt and updatedAt fields.
+// Tests may replace it to get predictable timestamps.
+var Now = time.Now
+
+// returns the index of the field tagged with timestamp option o, or nil.
+// Panics if the field is neither an integer nor a time.Time.
+func getTimestampField(t reflect.Type, o string) []int {
+	sf, ok := getOptionField(t, o)
+	if !ok {
+		return nil
+	}
+	if !isIntKind(sf.Type.Kind()) && sf.Type != timeType {
+		panic(&TagOptionKindError{o, sf.Type.Kind()})
+	}
+	return sf.Index
+}
+
+// reports whether an integer or time.Time timestamp is unset
+func isZeroTimestamp(v reflect.Value) bool {
+	if v.Type() == timeType {
+		return v.Interface().(time.Time).IsZero()
+	}
+	return v.Int() == 0
+}
+
+// writes the createdAt and updatedAt attributes of v into item
+func applyTimestamps(v reflect.Value, item map[string]*dynamodb.AttributeValue) {
+	t := v.Type()
+	now := strconv.FormatInt(Now().Unix(), 10)
+	if ci := getTimestampField(t, tagCreatedAt); ci != nil && isZeroTimestamp(v.FieldByIndex(ci)) {
+		item[getAttrName(t.FieldByIndex(ci))] = &dynamodb.AttributeValue{N: &now}
+	}
+	if ui := getTimestampField(t, tagUpdatedAt); ui != nil {
+		item[getAttrName(t.FieldByIndex(ui))] = &dynamodb.AttributeValue{N: &now}
+	}
+}
+
+// the attribute names an update may only write if they are not yet set
+func createOnlyAttrNames(t reflect.Type) []string {
+	if ci := getTimestampField(t, tagCreatedAt); ci != nil {
+		return []string{getAttrName(t.FieldByIndex(ci))}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+// Copyright 2016 Appittome. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dynaGo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/japhyf/dynaGo/dynagotest"
+)
+
+type Note struct {
+	Id      string `dynaGo:"NoteId,HASH"`
+	Body    string
+	Created int64 `dynaGo:",createdAt"`
+	Updated int64 `dynaGo:",updatedAt"`
+}
+
+func TestMarshalTimestamps(t *testing.T) {
+	defer func(now func() time.Time) { Now = now }(Now)
+	Now = func() time.Time { return time.Unix(1000, 0) }
+
+	in := Marshal(Note{Id: "a"})
+	if *in.Item["Created"].N != "1000" || *in.Item["Updated"].N != "1000" {
+		t.Errorf("expected timestamps of 1000, found %v", in.Item)
+	}
+	in = Marshal(Note{Id: "a", Created: 10})
+	if *in.Item["Created"].N != "10" || *in.Item["Updated"].N != "1000" {
+		t.Errorf("expected createdAt to be kept, found %v", in.Item)
+	}
+
+	up := MarshalUpdate(Note{Id: "a", Body: "b"})
+	if *up.UpdateExpression != "SET #n0 = :v0, #n1 = if_not_exists(#n1, :v1), #n2 = :v2" {
+		t.Errorf("unexpected update expression: %s", *up.UpdateExpression)
+	}
+}
+
+func TestCreatedAtFirstWrite(t *testing.T) {
+	defer func(now func() time.Time) { Now = now }(Now)
+	db := dynagotest.New()
+	if err := CreateTable(db, Note{}, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	created := func() int64 {
+		n := Note{Id: "a"}
+		if err := Get(db, &n); err != nil {
+			t.Fatal(err)
+		}
+		return n.Created
+	}
+
+	Now = func() time.Time { return time.Unix(1000, 0) }
+	if err := Put(db, Note{Id: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	Now = func() time.Time { return time.Unix(2000, 0) }
+	if err := Update(db, Note{Id: "a", Body: "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if c := created(); c != 1000 {
+		t.Errorf("expected Update to keep createdAt, found %d", c)
+	}
+	// Put replaces the item, createdAt included
+	if err := Put(db, Note{Id: "a", Body: "c"}); err != nil {
+		t.Fatal(err)
+	}
+	if c := created(); c != 2000 {
+		t.Errorf("expected Put to stamp createdAt, found %d", c)
+	}
+}
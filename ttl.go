@@ -0,0 +1,68 @@
+// Copyright 2016 Appittome. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dynaGo
+
+import (
+	"reflect"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// dynamoDB expires items using a numeric attribute holding a unix
+// timestamp (seconds).  Such an attribute is declared by tagging an
+// integer or time.Time field with the "ttl" option:
+//   Expires time.Time `dynaGo:",ttl"`
+// Both kinds are written as epoch seconds by Marshal.  CreateTable
+// enables time to live on the attribute, EnableTimeToLive does the same
+// for tables that already exist.
+const tagTTL = "ttl"
+
+// returns the field tagged "ttl", panics if the field is neither an
+// integer nor a time.Time.
+func getTTLField(t reflect.Type) (reflect.StructField, bool) {
+	sf, ok := getOptionField(t, tagTTL)
+	if ok && !isIntKind(sf.Type.Kind()) && sf.Type != timeType {
+		panic(&TagOptionKindError{tagTTL, sf.Type.Kind()})
+	}
+	return sf, ok
+}
+
+// EnableTimeToLive turns on time to live for the table of v, using the
+// attribute of the field tagged "ttl".  It does nothing if v has no such
+// field.  The table must be ACTIVE.
+func EnableTimeToLive(svc dynamodbiface.DynamoDBAPI, v interface{}) error {
+	return EnableTimeToLiveWithContext(aws.BackgroundContext(), svc, v)
+}
+
+// EnableTimeToLiveWithContext is EnableTimeToLive with a context and
+// request options for the request made.
+func EnableTimeToLiveWithContext(ctx aws.Context, svc dynamodbiface.DynamoDBAPI, v interface{}, opts ...request.Option) error {
+	params := TimeToLiveInput(v)
+	if params == nil {
+		return nil
+	}
+	_, err := svc.UpdateTimeToLiveWithContext(ctx, params, requestOptions(ctx, opts)...)
+	return err
+}
+
+// TimeToLiveInput returns the dynamodb.UpdateTimeToLiveInput
+// EnableTimeToLive would send for v, nil if v has no field tagged "ttl".
+func TimeToLiveInput(v interface{}) *dynamodb.UpdateTimeToLiveInput {
+	t := reflectType(v)
+	sf, ok := getTTLField(t)
+	if !ok {
+		return nil
+	}
+	return &dynamodb.UpdateTimeToLiveInput{
+		TableName: aws.String(TableName(t)),
+		TimeToLiveSpecification: &dynamodb.TimeToLiveSpecification{
+			AttributeName: aws.String(getAttrName(sf)),
+			Enabled:       aws.Bool(true),
+		},
+	}
+}
@@ -0,0 +1,30 @@
+// Copyright 2016 Appittome. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dynaGo
+
+import (
+	"testing"
+	"time"
+)
+
+type Lease struct {
+	Id      string    `dynaGo:"LeaseId,HASH"`
+	Expires time.Time `dynaGo:",ttl"`
+}
+
+func TestMarshalTTL(t *testing.T) {
+	l := Lease{Id: "a", Expires: time.Unix(1500000000, 0)}
+	in := Marshal(l)
+	if *in.Item["Expires"].N != "1500000000" {
+		t.Errorf("expected epoch seconds, found %v", in.Item["Expires"])
+	}
+	var d Lease
+	if err := Unmarshal(in.Item, &d); err != nil {
+		t.Fatal(err)
+	}
+	if !d.Expires.Equal(l.Expires) {
+		t.Errorf("expected %s, found %s", l.Expires, d.Expires)
+	}
+}
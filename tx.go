@@ -0,0 +1,375 @@
+// Copyright 2016 Appittome. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dynaGo
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// the most operations dynamoDB accepts in one transaction
+const txLimit = 100
+
+// The kinds of TxOp
+const (
+	TxPut            = "Put"
+	TxUpdate         = "Update"
+	TxDelete         = "Delete"
+	TxConditionCheck = "ConditionCheck"
+)
+
+// Condition is a condition expression with the placeholders it uses,
+// for Tx.ConditionCheck and Tx.DeleteIf.
+type Condition struct {
+	Expression string
+	Names      map[string]*string
+	Values     map[string]*dynamodb.AttributeValue
+}
+
+// TxOp describes one operation of a transaction.  Item is the value
+// given to Put or Update, and nil for other kinds.
+type TxOp struct {
+	Kind      string
+	TableName string
+	Key       map[string]*dynamodb.AttributeValue
+	Item      interface{}
+}
+
+// Tx collects Put, Update, Delete and ConditionCheck operations on any
+// dynaGo types and writes them atomically with TransactWriteItems:
+//   err := NewTx().Put(&ses).Put(&msg).Commit(svc)
+// dynamoDB limits a transaction to 100 operations and allows each item
+// to be touched only once, both are checked as operations are added.
+// The first error found is returned by Commit, which then sends nothing.
+type Tx struct {
+	items  []*dynamodb.TransactWriteItem
+	ops    []TxOp
+	syncs  []func()
+	keys   map[string]bool
+	claims []txClaim
+	err    error
+}
+
+// the operation at index op puts the sentinel of a unique value
+type txClaim struct {
+	op    int
+	attr  string
+	value string
+}
+
+func NewTx() *Tx {
+	return &Tx{keys: make(map[string]bool)}
+}
+
+// Put adds a put of i, encoded by Marshal with all of its conditions.
+// If i has "unique" fields, the puts of their sentinels are added too,
+// claiming the values as new (see unique.go).
+func (tx *Tx) Put(i interface{}) *Tx {
+	item := tx.put(i, nil)
+	t := reflectType(i)
+	for _, u := range getUniqueAttrNames(t) {
+		if uv := uniqueValue(item[u]); uv != "" {
+			tx.claim(t, u, uv)
+		}
+	}
+	return tx
+}
+
+// adds a put of i, also conditioned on guard when it is not nil, and
+// returns the item put.
+func (tx *Tx) put(i interface{}, guard func(*expression) string) map[string]*dynamodb.AttributeValue {
+	in := Marshal(i)
+	t := reflectType(i)
+	if guard != nil {
+		x := expressionFrom(in.ExpressionAttributeNames, in.ExpressionAttributeValues)
+		in.ConditionExpression = andCondition(in.ConditionExpression, guard(x))
+		in.ExpressionAttributeNames, in.ExpressionAttributeValues = x.attributeNames(), x.attributeValues()
+	}
+	op := TxOp{TxPut, *in.TableName, itemKey(t, "", in.Item), i}
+	tx.add(op, &dynamodb.TransactWriteItem{
+		Put: &dynamodb.Put{
+			TableName:                 in.TableName,
+			Item:                      in.Item,
+			ConditionExpression:       in.ConditionExpression,
+			ExpressionAttributeNames:  in.ExpressionAttributeNames,
+			ExpressionAttributeValues: in.ExpressionAttributeValues,
+		},
+	}, func() { syncItem(i, in.Item) })
+	return in.Item
+}
+
+// Update adds an update of i, encoded by MarshalUpdate.  Unique
+// sentinels are not maintained, use the Update function for that.
+func (tx *Tx) Update(i interface{}) *Tx {
+	tx.update(i, nil)
+	return tx
+}
+
+// adds an update of i, also conditioned on guard when it is not nil,
+// and returns the attributes set.
+func (tx *Tx) update(i interface{}, guard func(*expression) string) map[string]*dynamodb.AttributeValue {
+	in, item := marshalUpdate(i)
+	if guard != nil {
+		x := expressionFrom(in.ExpressionAttributeNames, in.ExpressionAttributeValues)
+		in.ConditionExpression = andCondition(in.ConditionExpression, guard(x))
+		in.ExpressionAttributeNames, in.ExpressionAttributeValues = x.attributeNames(), x.attributeValues()
+	}
+	// the stored createdAt may be older than the one written
+	for _, n := range createOnlyAttrNames(reflectType(i)) {
+		delete(item, n)
+	}
+	op := TxOp{TxUpdate, *in.TableName, in.Key, i}
+	tx.add(op, &dynamodb.TransactWriteItem{
+		Update: &dynamodb.Update{
+			TableName:                 in.TableName,
+			Key:                       in.Key,
+			UpdateExpression:          in.UpdateExpression,
+			ConditionExpression:       in.ConditionExpression,
+			ExpressionAttributeNames:  in.ExpressionAttributeNames,
+			ExpressionAttributeValues: in.ExpressionAttributeValues,
+		},
+	}, func() { syncItem(i, item) })
+	return item
+}
+
+// Delete adds a delete of the item with key kv.  Unique sentinels are
+// not removed, use the Delete function for that.
+func (tx *Tx) Delete(km KeyMaker, kv ...interface{}) *Tx {
+	return tx.DeleteIf(Condition{}, km, kv...)
+}
+
+// DeleteIf adds a delete of the item with key kv, made only if c holds.
+// An empty c.Expression deletes unconditionally.
+func (tx *Tx) DeleteIf(c Condition, km KeyMaker, kv ...interface{}) *Tx {
+	k, err := km(kv...)
+	if err != nil {
+		return tx.fail(err)
+	}
+	d := &dynamodb.Delete{TableName: aws.String(k.tbln), Key: k.attr}
+	if c.Expression != "" {
+		d.ConditionExpression = aws.String(c.Expression)
+		d.ExpressionAttributeNames, d.ExpressionAttributeValues = c.Names, c.Values
+	}
+	return tx.add(TxOp{TxDelete, k.tbln, k.attr, nil}, &dynamodb.TransactWriteItem{Delete: d}, nil)
+}
+
+// ConditionCheck adds a check that c holds for the item with key kv,
+// without writing it.
+func (tx *Tx) ConditionCheck(c Condition, km KeyMaker, kv ...interface{}) *Tx {
+	k, err := km(kv...)
+	if err != nil {
+		return tx.fail(err)
+	}
+	return tx.add(TxOp{TxConditionCheck, k.tbln, k.attr, nil}, &dynamodb.TransactWriteItem{
+		ConditionCheck: &dynamodb.ConditionCheck{
+			TableName:                 aws.String(k.tbln),
+			Key:                       k.attr,
+			ConditionExpression:       aws.String(c.Expression),
+			ExpressionAttributeNames:  c.Names,
+			ExpressionAttributeValues: c.Values,
+		},
+	}, nil)
+}
+
+// Ops returns the operations added so far, in order.
+func (tx *Tx) Ops() []TxOp {
+	return tx.ops
+}
+
+// Commit writes all operations in a single TransactWriteItems call.  If
+// dynamoDB cancels the transaction, a *TxCanceledError relates each
+// cancellation reason to the operation it concerns.  After a successful
+// commit the version and timestamp fields of items given as pointers
+// are updated, as Put and Update do.
+func (tx *Tx) Commit(svc dynamodbiface.DynamoDBAPI) error {
+	return tx.CommitWithContext(aws.BackgroundContext(), svc)
+}
+
+// CommitWithContext is Commit with a context and request options for the
+// request made.
+func (tx *Tx) CommitWithContext(ctx aws.Context, svc dynamodbiface.DynamoDBAPI, opts ...request.Option) error {
+	if tx.err != nil {
+		return tx.err
+	}
+	if len(tx.items) == 0 {
+		return nil
+	}
+	_, err := svc.TransactWriteItemsWithContext(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: tx.items,
+	}, requestOptions(ctx, opts)...)
+	if err != nil {
+		return txError(tx.ops, tx.claims, err)
+	}
+	for _, sync := range tx.syncs {
+		if sync != nil {
+			sync()
+		}
+	}
+	return nil
+}
+
+func (tx *Tx) add(op TxOp, item *dynamodb.TransactWriteItem, sync func()) *Tx {
+	if tx.err != nil {
+		return tx
+	}
+	if len(tx.items) == txLimit {
+		return tx.fail(&TxTooLargeError{txLimit})
+	}
+	id := op.TableName + "\x00" + keyString(op.Key)
+	if tx.keys[id] {
+		return tx.fail(&TxDuplicateItemError{op})
+	}
+	tx.keys[id] = true
+	tx.items = append(tx.items, item)
+	tx.ops = append(tx.ops, op)
+	tx.syncs = append(tx.syncs, sync)
+	return tx
+}
+
+func (tx *Tx) fail(err error) *Tx {
+	if tx.err == nil {
+		tx.err = err
+	}
+	return tx
+}
+
+// maps the cancellation reasons of a canceled transaction to its
+// operations.  dynamoDB lists one reason per operation, in order, with
+// code "None" for those that did not fail.
+func txError(ops []TxOp, claims []txClaim, err error) error {
+	tce, ok := err.(*dynamodb.TransactionCanceledException)
+	if !ok {
+		return err
+	}
+	fs := make([]TxFailure, 0)
+	for n, r := range tce.CancellationReasons {
+		code := aws.StringValue(r.Code)
+		if n >= len(ops) || code == "" || code == "None" {
+			continue
+		}
+		f := TxFailure{Op: ops[n], Code: code, Message: aws.StringValue(r.Message)}
+		if code == "ConditionalCheckFailed" && ops[n].Item != nil {
+			v := reflect.Indirect(reflect.ValueOf(ops[n].Item))
+			if vi := getVersionField(v.Type()); vi != nil {
+				f.Err = &VersionConflictError{ops[n].TableName, v.FieldByIndex(vi).Int(), err}
+			}
+		}
+		for _, c := range claims {
+			if c.op == n && code == "ConditionalCheckFailed" {
+				f.Err = &UniqueConstraintError{ops[n].TableName, c.attr, c.value}
+			}
+		}
+		fs = append(fs, f)
+	}
+	return &TxCanceledError{Failures: fs, Err: err}
+}
+
+// TxGet reads several items consistently with TransactGetItems, each
+// decoded into its own destination:
+//   var ses Session
+//   var usr Usr
+//   err := NewTxGet().Get(&ses, sesKm, "1000", "abc").Get(&usr, usrKm, "1000").Run(svc)
+// A destination whose item does not exist is left unchanged.
+type TxGet struct {
+	items []*dynamodb.TransactGetItem
+	dsts  []interface{}
+	err   error
+}
+
+func NewTxGet() *TxGet {
+	return &TxGet{}
+}
+
+// Get adds a read of the item with key kv, to be decoded into dst.
+func (g *TxGet) Get(dst interface{}, km KeyMaker, kv ...interface{}) *TxGet {
+	if g.err != nil {
+		return g
+	}
+	if len(g.items) == txLimit {
+		g.err = &TxTooLargeError{txLimit}
+		return g
+	}
+	k, err := km(kv...)
+	if err != nil {
+		g.err = err
+		return g
+	}
+	g.items = append(g.items, &dynamodb.TransactGetItem{
+		Get: &dynamodb.Get{TableName: aws.String(k.tbln), Key: k.attr},
+	})
+	g.dsts = append(g.dsts, dst)
+	return g
+}
+
+// Run reads all items in a single TransactGetItems call.
+func (g *TxGet) Run(svc dynamodbiface.DynamoDBAPI) error {
+	return g.RunWithContext(aws.BackgroundContext(), svc)
+}
+
+// RunWithContext is Run with a context and request options for the
+// request made.
+func (g *TxGet) RunWithContext(ctx aws.Context, svc dynamodbiface.DynamoDBAPI, opts ...request.Option) error {
+	if g.err != nil {
+		return g.err
+	}
+	if len(g.items) == 0 {
+		return nil
+	}
+	resp, err := svc.TransactGetItemsWithContext(ctx, &dynamodb.TransactGetItemsInput{
+		TransactItems: g.items,
+	}, requestOptions(ctx, opts)...)
+	if err != nil {
+		return err
+	}
+	for n, r := range resp.Responses {
+		if len(r.Item) == 0 || n >= len(g.dsts) {
+			continue
+		}
+		if err := Unmarshal(r.Item, g.dsts[n]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// eg. "Put Usrs {UserId: 1000}"
+func (op TxOp) String() string {
+	ns := make([]string, 0, len(op.Key))
+	for n := range op.Key {
+		ns = append(ns, n)
+	}
+	sort.Strings(ns)
+	ks := make([]string, 0, len(ns))
+	for _, n := range ns {
+		av := op.Key[n]
+		switch {
+		case av.S != nil:
+			ks = append(ks, n+": "+*av.S)
+		case av.N != nil:
+			ks = append(ks, n+": "+*av.N)
+		default:
+			ks = append(ks, n+": "+fmt.Sprintf("% x", av.B))
+		}
+	}
+	return op.Kind + " " + op.TableName + " {" + strings.Join(ks, ", ") + "}"
+}
+
+// TxFailure is the reason dynamoDB gave for canceling a transaction on
+// account of Op.  Err is a *VersionConflictError when a versioned Put or
+// Update failed its condition, and a *UniqueConstraintError when the
+// sentinel of a unique value already exists.
+type TxFailure struct {
+	Op      TxOp
+	Code    string
+	Message string
+	Err     error
+}
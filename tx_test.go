@@ -0,0 +1,55 @@
+// Copyright 2016 Appittome. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dynaGo
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func TestTxRules(t *testing.T) {
+	km := CreateKeyMaker(reflect.TypeOf(Usr{}))
+	tx := NewTx().Put(usr0).Delete(km, usr0.Id)
+	if _, ok := tx.err.(*TxDuplicateItemError); !ok {
+		t.Errorf("expected a duplicate item error, found %v", tx.err)
+	}
+
+	tx = NewTx()
+	for n := 0; n <= txLimit; n++ {
+		tx.Delete(km, strconv.Itoa(n))
+	}
+	if _, ok := tx.err.(*TxTooLargeError); !ok {
+		t.Errorf("expected a too large error, found %v", tx.err)
+	}
+}
+
+func TestTxError(t *testing.T) {
+	tx := NewTx().Put(&Doc{Id: "a", Version: 3}).Put(msg)
+	err := txError(tx.Ops(), nil, &dynamodb.TransactionCanceledException{
+		CancellationReasons: []*dynamodb.CancellationReason{
+			{Code: aws.String("ConditionalCheckFailed")},
+			{Code: aws.String("None")},
+		},
+	})
+	tce, ok := err.(*TxCanceledError)
+	if !ok || len(tce.Failures) != 1 {
+		t.Fatalf("expected a single failure, found %v", err)
+	}
+	if tce.Failures[0].Op.Kind != TxPut || tce.Failures[0].Op.TableName != "Docs" {
+		t.Errorf("failure related to the wrong operation: %v", tce.Failures[0].Op)
+	}
+	if vce, ok := tce.Failures[0].Err.(*VersionConflictError); !ok || vce.Version != 3 {
+		t.Errorf("expected a version conflict, found %v", tce.Failures[0].Err)
+	}
+	var tcx *dynamodb.TransactionCanceledException
+	if !errors.As(err, &tcx) || len(tcx.CancellationReasons) != 2 {
+		t.Errorf("expected the cancellation to be wrapped, found %v", tce.Err)
+	}
+}
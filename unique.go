@@ -0,0 +1,207 @@
+// Copyright 2016 Appittome. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dynaGo
+
+import (
+	"reflect"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// dynamoDB only guarantees that primary keys are unique.  A string or
+// integer field tagged "unique" is made unique within its table by a
+// sentinel item, written in the same transaction as the item itself:
+//   Email string `dynaGo:",unique"`
+// The sentinel of bob@home.org in Usrs has the partition key
+// "Usrs#Email#bob@home.org" (and the same sort key, if the table has
+// one), and is only put if it does not exist yet.  Put and Update move
+// sentinels when the value changes, Delete removes them.  Sentinels are
+// marked with the sentinelAttr attribute, and skipped by iterators and
+// BatchGet.
+//
+// Sentinels need a string partition key to hold their value, so
+// "unique" cannot be used on a type whose partition key is a number.
+const tagUnique = "unique"
+
+// holds the attribute name the sentinel guards
+const sentinelAttr = "_unique"
+
+// the attribute names of the fields tagged "unique".  Panics if a field
+// is neither a string nor an integer, or the partition key of t is not
+// a string.
+func getUniqueAttrNames(t reflect.Type) []string {
+	ns := make([]string, 0)
+	for n := 0; n < t.NumField(); n++ {
+		f := t.Field(n)
+		if _, opts := parseTag(f.Tag.Get("dynaGo")); !opts.Contains(tagUnique) {
+			continue
+		}
+		if f.Type.Kind() != reflect.String && !isIntKind(f.Type.Kind()) {
+			panic(&TagOptionKindError{tagUnique, f.Type.Kind()})
+		}
+		ns = append(ns, getAttrName(f))
+	}
+	if len(ns) > 0 {
+		ads := attributeDefinitionMap(typeSchema(t).AttributeDefinitions)
+		if ads[keyAttrNames(t)[0]] != dynamodb.ScalarAttributeTypeS {
+			panic(&TagOptionKindError{tagUnique, t.Field(getPartitionKey(t)[0]).Type.Kind()})
+		}
+	}
+	return ns
+}
+
+// the string form of a unique attribute, "" if absent
+func uniqueValue(av *dynamodb.AttributeValue) string {
+	switch {
+	case av == nil:
+		return ""
+	case av.S != nil:
+		return *av.S
+	default:
+		return aws.StringValue(av.N)
+	}
+}
+
+func isSentinel(item map[string]*dynamodb.AttributeValue) bool {
+	_, ok := item[sentinelAttr]
+	return ok
+}
+
+// the key of the sentinel guarding value uv of attribute u in the table
+// of t.  A sort key is given the sentinel string as well, or 0 if it is
+// numeric.
+func sentinelKey(t reflect.Type, u, uv string) map[string]*dynamodb.AttributeValue {
+	sv := TableName(t) + "#" + u + "#" + uv
+	ads := attributeDefinitionMap(typeSchema(t).AttributeDefinitions)
+	k := make(map[string]*dynamodb.AttributeValue)
+	for _, n := range keyAttrNames(t) {
+		if ads[n] == dynamodb.ScalarAttributeTypeN {
+			k[n] = &dynamodb.AttributeValue{N: aws.String("0")}
+			continue
+		}
+		k[n] = &dynamodb.AttributeValue{S: aws.String(sv)}
+	}
+	return k
+}
+
+// adds the put of a sentinel claiming value uv of attribute u
+func (tx *Tx) claim(t reflect.Type, u, uv string) {
+	k := sentinelKey(t, u, uv)
+	item := map[string]*dynamodb.AttributeValue{sentinelAttr: {S: aws.String(u)}}
+	for n, av := range k {
+		item[n] = av
+	}
+	tn := TableName(t)
+	tx.add(TxOp{TxPut, tn, k, nil}, &dynamodb.TransactWriteItem{
+		Put: &dynamodb.Put{
+			TableName:                aws.String(tn),
+			Item:                     item,
+			ConditionExpression:      aws.String("attribute_not_exists(#n0)"),
+			ExpressionAttributeNames: map[string]*string{"#n0": aws.String(keyAttrNames(t)[0])},
+		},
+	}, nil)
+	tx.claims = append(tx.claims, txClaim{len(tx.ops) - 1, u, uv})
+}
+
+// adds the delete of the sentinel of value uv of attribute u
+func (tx *Tx) release(t reflect.Type, u, uv string) {
+	k := sentinelKey(t, u, uv)
+	tn := TableName(t)
+	tx.add(TxOp{TxDelete, tn, k, nil}, &dynamodb.TransactWriteItem{
+		Delete: &dynamodb.Delete{TableName: aws.String(tn), Key: k},
+	}, nil)
+}
+
+// a condition that the unique attributes of the stored item still have
+// the values found in old, or that the item does not exist if old is nil
+func uniqueGuard(t reflect.Type, old map[string]*dynamodb.AttributeValue) func(*expression) string {
+	return func(x *expression) string {
+		if old == nil {
+			return "attribute_not_exists(" + x.name(keyAttrNames(t)[0]) + ")"
+		}
+		cs := make([]string, 0)
+		for _, u := range getUniqueAttrNames(t) {
+			if av, ok := old[u]; ok {
+				cs = append(cs, x.name(u)+" = "+x.value(av))
+			} else {
+				cs = append(cs, "attribute_not_exists("+x.name(u)+")")
+			}
+		}
+		return *conditionExpression(cs)
+	}
+}
+
+// writes i with Put (or Update) semantics in a transaction that moves
+// the sentinels of its unique attributes whose values changed.
+func uniqueWrite(ctx aws.Context, svc dynamodbiface.DynamoDBAPI, i interface{}, update bool, opts []request.Option) error {
+	t := reflectType(i)
+	old, err := getStored(ctx, svc, t, itemKey(t, "", Marshal(i).Item), opts)
+	if err != nil {
+		return err
+	}
+	tx := NewTx()
+	var item map[string]*dynamodb.AttributeValue
+	if update {
+		item = tx.update(i, uniqueGuard(t, old))
+	} else {
+		item = tx.put(i, uniqueGuard(t, old))
+	}
+	for _, u := range getUniqueAttrNames(t) {
+		nv, ov := uniqueValue(item[u]), uniqueValue(old[u])
+		// an update leaves an absent attribute as it was
+		if nv == ov || (update && nv == "") {
+			continue
+		}
+		if ov != "" {
+			tx.release(t, u, ov)
+		}
+		if nv != "" {
+			tx.claim(t, u, nv)
+		}
+	}
+	return tx.CommitWithContext(ctx, svc, opts...)
+}
+
+// deletes the item with key k and the sentinels of its unique values
+func uniqueDelete(ctx aws.Context, svc dynamodbiface.DynamoDBAPI, k key, opts []request.Option) error {
+	old, err := getStored(ctx, svc, k.typ, k.attr, opts)
+	if err != nil || old == nil {
+		return err
+	}
+	tx := NewTx()
+	x := newExpression()
+	c := uniqueGuard(k.typ, old)(x)
+	tx.add(TxOp{TxDelete, k.tbln, k.attr, nil}, &dynamodb.TransactWriteItem{
+		Delete: &dynamodb.Delete{
+			TableName:                 aws.String(k.tbln),
+			Key:                       k.attr,
+			ConditionExpression:       &c,
+			ExpressionAttributeNames:  x.attributeNames(),
+			ExpressionAttributeValues: x.attributeValues(),
+		},
+	}, nil)
+	for _, u := range getUniqueAttrNames(k.typ) {
+		if ov := uniqueValue(old[u]); ov != "" {
+			tx.release(k.typ, u, ov)
+		}
+	}
+	return tx.CommitWithContext(ctx, svc, opts...)
+}
+
+// a consistent read of the item with key k, nil if there is none
+func getStored(ctx aws.Context, svc dynamodbiface.DynamoDBAPI, t reflect.Type, k map[string]*dynamodb.AttributeValue, opts []request.Option) (map[string]*dynamodb.AttributeValue, error) {
+	resp, err := svc.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(TableName(t)),
+		Key:            k,
+		ConsistentRead: aws.Bool(true),
+	}, requestOptions(ctx, opts)...)
+	if err != nil || len(resp.Item) == 0 {
+		return nil, err
+	}
+	return resp.Item, nil
+}
@@ -0,0 +1,100 @@
+// Copyright 2016 Appittome. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dynaGo
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/japhyf/dynaGo/dynagotest"
+)
+
+type Account struct {
+	Id    string `dynaGo:"AccountId,HASH"`
+	Email string `dynaGo:",unique"`
+	Alias string `dynaGo:",unique"`
+}
+
+func TestTxPutClaimsUniqueValues(t *testing.T) {
+	tx := NewTx().Put(Account{Id: "1", Email: "bob@home.org"})
+	if tx.err != nil {
+		t.Fatal(tx.err)
+	}
+	ops := tx.Ops()
+	if len(ops) != 2 {
+		t.Fatalf("expected the item and one sentinel, found %v", ops)
+	}
+	if k := *ops[1].Key["AccountId"].S; k != "Accounts#Email#bob@home.org" {
+		t.Errorf("unexpected sentinel key %s", k)
+	}
+	if len(tx.claims) != 1 || tx.claims[0].op != 1 {
+		t.Errorf("unexpected claims %v", tx.claims)
+	}
+
+	tx = NewTx().Put(Account{Id: "1", Email: "bob@home.org"}).Put(Account{Id: "2", Email: "bob@home.org"})
+	if _, ok := tx.err.(*TxDuplicateItemError); !ok {
+		t.Errorf("expected the second claim of a value to be refused, found %v", tx.err)
+	}
+}
+
+func TestUniqueWrites(t *testing.T) {
+	db := dynagotest.New()
+	if err := CreateTable(db, Account{}, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := Put(db, Account{Id: "1", Email: "a@x", Alias: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Put(db, Account{Id: "2", Email: "b@x", Alias: "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := Put(db, Account{Id: "3", Email: "a@x"}).(*UniqueConstraintError); !ok {
+		t.Error("expected a@x to be taken")
+	}
+	// moving 1 to c@x releases a@x
+	if err := Put(db, Account{Id: "1", Email: "c@x", Alias: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Put(db, Account{Id: "3", Email: "a@x"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Delete(db, CreateKeyMaker(reflect.TypeOf(Account{})), "2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Put(db, Account{Id: "4", Email: "b@x", Alias: "b"}); err != nil {
+		t.Errorf("expected the values of the deleted item to be released, found %v", err)
+	}
+}
+
+type NumberedAccount struct {
+	Id    int64  `dynaGo:",HASH"`
+	Email string `dynaGo:",unique"`
+}
+
+func TestUniqueNeedsStringPartitionKey(t *testing.T) {
+	defer func() {
+		if _, ok := recover().(*TagOptionKindError); !ok {
+			t.Error("expected a TagOptionKindError")
+		}
+	}()
+	Put(dynagotest.New(), NumberedAccount{Id: 1, Email: "a@x"})
+}
+
+func TestUniqueNotBatched(t *testing.T) {
+	b := &dynamodb.BatchWriteItemInput{}
+	if err := AppendPutToBatchWrite(b, Account{Id: "1", Email: "bob@home.org"}); err == nil {
+		t.Error("expected a batch put of unique values to be refused")
+	} else if _, ok := err.(*BatchConditionError); !ok {
+		t.Errorf("expected a *BatchConditionError, found %v", err)
+	}
+	err := AppendDeleteToBatchWrite(b, CreateKeyMaker(reflect.TypeOf(Account{})), "1")
+	if _, ok := err.(*BatchConditionError); !ok {
+		t.Errorf("expected a batch delete of unique values to be refused, found %v", err)
+	}
+	if len(b.RequestItems) != 0 {
+		t.Errorf("expected no write requests, found %v", b.RequestItems)
+	}
+}
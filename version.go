@@ -0,0 +1,54 @@
+// Copyright 2016 Appittome. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dynaGo
+
+import (
+	"reflect"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// Optimistic locking is enabled by tagging an integer field with the
+// "version" option:
+//   Version int64 `dynaGo:",version"`
+// Every write made through Marshal, MarshalUpdate, Put or Update then
+// expects the stored item to hold the version found in the struct, and
+// writes that version plus one.  A zero version expects the item (or at
+// least its version attribute) not to exist yet.
+const tagVersion = "version"
+
+// returns the index of the field tagged "version", or nil if the
+// struct is not versioned.  Panics if the field is not an integer.
+func getVersionField(t reflect.Type) []int {
+	sf, ok := getOptionField(t, tagVersion)
+	if !ok {
+		return nil
+	}
+	if !isIntKind(sf.Type.Kind()) {
+		panic(&TagOptionKindError{tagVersion, sf.Type.Kind()})
+	}
+	return sf.Index
+}
+
+// replaces the version attribute of item with the next version and
+// returns the condition guarding the write against concurrent updates.
+// returns "" if v is not versioned.
+func versionCondition(x *expression, v reflect.Value, item map[string]*dynamodb.AttributeValue) string {
+	vi := getVersionField(v.Type())
+	if vi == nil {
+		return ""
+	}
+	an := getAttrName(v.Type().FieldByIndex(vi))
+	cur := v.FieldByIndex(vi).Int()
+	next := strconv.FormatInt(cur+1, 10)
+	item[an] = &dynamodb.AttributeValue{N: &next}
+
+	if cur == 0 {
+		return "attribute_not_exists(" + x.name(an) + ")"
+	}
+	expected := strconv.FormatInt(cur, 10)
+	return x.name(an) + " = " + x.value(&dynamodb.AttributeValue{N: &expected})
+}
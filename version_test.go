@@ -0,0 +1,60 @@
+// Copyright 2016 Appittome. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dynaGo
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/japhyf/dynaGo/dynagotest"
+)
+
+type Doc struct {
+	Id      string `dynaGo:"DocId,HASH"`
+	Body    string
+	Version int64 `dynaGo:",version"`
+}
+
+func TestMarshalVersion(t *testing.T) {
+	in := Marshal(Doc{Id: "a", Body: "new"})
+	if *in.ConditionExpression != "attribute_not_exists(#n0)" {
+		t.Errorf("unexpected condition for first write: %s", *in.ConditionExpression)
+	}
+	if *in.Item["Version"].N != "1" {
+		t.Errorf("expected version 1, found %s", *in.Item["Version"].N)
+	}
+
+	up := MarshalUpdate(&Doc{Id: "a", Body: "newer", Version: 1})
+	if *up.ConditionExpression != "#n0 = :v0" || *up.ExpressionAttributeValues[":v0"].N != "1" {
+		t.Errorf("unexpected condition for second write: %s", *up.ConditionExpression)
+	}
+	if _, ok := up.Key["DocId"]; !ok || len(up.Key) != 1 {
+		t.Errorf("unexpected update key: %v", up.Key)
+	}
+	if *up.UpdateExpression != "SET #n1 = :v1, #n0 = :v2" {
+		t.Errorf("unexpected update expression: %s", *up.UpdateExpression)
+	}
+}
+
+func TestVersionConflictUnwraps(t *testing.T) {
+	db := dynagotest.New()
+	if err := CreateTable(db, Doc{}, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := Put(db, Doc{Id: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	err := Put(db, Doc{Id: "a"})
+	var vce *VersionConflictError
+	if !errors.As(err, &vce) {
+		t.Fatalf("expected a version conflict, found %v", err)
+	}
+	var aerr awserr.Error
+	if !errors.As(err, &aerr) || aerr.Code() != dynamodb.ErrCodeConditionalCheckFailedException {
+		t.Errorf("expected the failed condition to be wrapped, found %v", vce.Err)
+	}
+}
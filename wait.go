@@ -0,0 +1,117 @@
+// Copyright 2016 Appittome. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dynaGo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/japhyf/dynaGo/internal/wait"
+)
+
+// WaitOptions controls the DescribeTable polling used to wait for table
+// status changes.  The delay between polls starts at Delay and doubles
+// up to MaxDelay.  A zero Timeout waits until the context is done.
+type WaitOptions struct {
+	Delay    time.Duration
+	MaxDelay time.Duration
+	Timeout  time.Duration
+}
+
+// used when no WaitOptions are given, or for zero delays
+var defaultWaitOptions = WaitOptions{
+	Delay:    wait.DefaultDelay,
+	MaxDelay: wait.DefaultMaxDelay,
+}
+
+// WaitUntilTableActive polls the table tn until both the table and all
+// of its global secondary indexes are ACTIVE, and no index is still
+// backfilling.  A table that is not found yet is polled again, since
+// DescribeTable may lag behind a CreateTable call.  opts apply to every
+// DescribeTable request.
+func WaitUntilTableActive(ctx aws.Context, svc dynamodbiface.DynamoDBAPI, tn string, o *WaitOptions, opts ...request.Option) error {
+	return waitForTable(ctx, svc, tn, o, opts, func(td *dynamodb.TableDescription) bool {
+		if td == nil || aws.StringValue(td.TableStatus) != dynamodb.TableStatusActive {
+			return false
+		}
+		for _, gsi := range td.GlobalSecondaryIndexes {
+			if aws.StringValue(gsi.IndexStatus) != dynamodb.IndexStatusActive || aws.BoolValue(gsi.Backfilling) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// WaitUntilTableDeleted polls the table tn until dynamoDB no longer
+// finds it.  opts apply to every DescribeTable request.
+func WaitUntilTableDeleted(ctx aws.Context, svc dynamodbiface.DynamoDBAPI, tn string, o *WaitOptions, opts ...request.Option) error {
+	return waitForTable(ctx, svc, tn, o, opts, func(td *dynamodb.TableDescription) bool {
+		return td == nil
+	})
+}
+
+// DeleteTable deletes the table of v.  If o is not nil, it waits until
+// the deletion has completed.
+func DeleteTable(svc dynamodbiface.DynamoDBAPI, v interface{}, o *WaitOptions) error {
+	return DeleteTableWithContext(aws.BackgroundContext(), svc, v, o)
+}
+
+// DeleteTableWithContext is DeleteTable with a context for the request
+// and the wait that follows, and request options for the requests made.
+func DeleteTableWithContext(ctx aws.Context, svc dynamodbiface.DynamoDBAPI, v interface{}, o *WaitOptions, opts ...request.Option) error {
+	tn := TableName(reflectType(v))
+	if _, err := svc.DeleteTableWithContext(ctx, &dynamodb.DeleteTableInput{TableName: &tn}, requestOptions(ctx, opts)...); err != nil {
+		return err
+	}
+	forgetTables(svc)
+	if o == nil {
+		return nil
+	}
+	return WaitUntilTableDeleted(ctx, svc, tn, o, opts...)
+}
+
+// o with the default delays in place of zero ones, nil is the default
+func (o *WaitOptions) withDefaults() WaitOptions {
+	wo := defaultWaitOptions
+	if o != nil {
+		wo.Timeout = o.Timeout
+		if o.Delay > 0 {
+			wo.Delay = o.Delay
+		}
+		if o.MaxDelay > 0 {
+			wo.MaxDelay = o.MaxDelay
+		}
+	}
+	return wo
+}
+
+// polls DescribeTable until done reports true.  done is handed a nil
+// description while the table is not found.
+func waitForTable(ctx aws.Context, svc dynamodbiface.DynamoDBAPI, tn string, o *WaitOptions, opts []request.Option, done func(*dynamodb.TableDescription) bool) error {
+	params := &dynamodb.DescribeTableInput{TableName: &tn}
+	return wait.Poll(ctx, wait.Backoff(o.withDefaults()), func(ctx context.Context) (bool, error) {
+		resp, err := svc.DescribeTableWithContext(ctx, params, requestOptions(ctx, opts)...)
+		switch {
+		case err == nil:
+			return done(resp.Table), nil
+		case isErrCode(err, dynamodb.ErrCodeResourceNotFoundException):
+			return done(nil), nil
+		}
+		return false, err
+	})
+}
+
+// reports whether err is, or wraps, an aws error with the given code
+func isErrCode(err error, code string) bool {
+	var aerr awserr.Error
+	return errors.As(err, &aerr) && aerr.Code() == code
+}
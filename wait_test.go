@@ -0,0 +1,102 @@
+// Copyright 2016 Appittome. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dynaGo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/japhyf/dynaGo/dynagotest"
+)
+
+func TestWaitDelay(t *testing.T) {
+	if wo := (*WaitOptions)(nil).withDefaults(); wo != defaultWaitOptions {
+		t.Errorf("expected the defaults, found %v", wo)
+	}
+	wo := (&WaitOptions{Delay: time.Millisecond, MaxDelay: 4 * time.Millisecond, Timeout: time.Second}).withDefaults()
+	if wo.Delay != time.Millisecond || wo.MaxDelay != 4*time.Millisecond {
+		t.Errorf("expected the given delays, found %v", wo)
+	}
+	if wo = (&WaitOptions{Timeout: time.Second}).withDefaults(); wo.Delay != defaultWaitOptions.Delay || wo.Timeout != time.Second {
+		t.Errorf("expected the default delays, found %v", wo)
+	}
+}
+
+func TestIsErrCode(t *testing.T) {
+	err := awserr.New(dynamodb.ErrCodeResourceNotFoundException, "not found", nil)
+	if !isErrCode(err, dynamodb.ErrCodeResourceNotFoundException) {
+		t.Error("expected the code to match")
+	}
+	if !isErrCode(&VersionConflictError{Err: err}, dynamodb.ErrCodeResourceNotFoundException) {
+		t.Error("expected the code of a wrapped error to match")
+	}
+	if isErrCode(errors.New("x"), dynamodb.ErrCodeResourceNotFoundException) {
+		t.Error("expected a plain error not to match")
+	}
+}
+
+func TestWaitUntilTableActive(t *testing.T) {
+	db := dynagotest.New()
+	db.StatusDelay = 3
+	r := &tableRecorder{DB: db}
+	if err := CreateTable(r, Message{}, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	o := &WaitOptions{Delay: time.Millisecond}
+	if err := WaitUntilTableActive(aws.BackgroundContext(), r, "Messages", o); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.calls) != 4 || r.calls[2] != "describe CREATING" || r.calls[3] != "describe ACTIVE" {
+		t.Errorf("unexpected calls %v", r.calls)
+	}
+
+	// a table that does not appear in time
+	o.Timeout = 10 * time.Millisecond
+	if err := WaitUntilTableActive(aws.BackgroundContext(), r, "Nothings", o); err != context.DeadlineExceeded {
+		t.Errorf("expected the wait to time out, found %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(aws.BackgroundContext())
+	cancel()
+	err := WaitUntilTableActive(ctx, r, "Nothings", &WaitOptions{Delay: time.Millisecond})
+	if !isErrCode(err, request.CanceledErrorCode) && err != context.Canceled {
+		t.Errorf("expected the wait to be canceled, found %v", err)
+	}
+}
+
+func TestDeleteTableWaits(t *testing.T) {
+	db := dynagotest.New()
+	if err := CreateTable(db, Message{}, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	db.StatusDelay = 2
+	r := &tableRecorder{DB: db}
+	if err := DeleteTable(r, Message{}, &WaitOptions{Delay: time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.calls) != 2 || r.calls[1] != "describe DELETING" {
+		t.Errorf("unexpected calls %v", r.calls)
+	}
+	_, err := db.DescribeTable(&dynamodb.DescribeTableInput{TableName: aws.String("Messages")})
+	if !isErrCode(err, dynamodb.ErrCodeResourceNotFoundException) {
+		t.Errorf("expected the table to be deleted, found %v", err)
+	}
+}
+
+func TestCreateTableTTLWaitTimesOut(t *testing.T) {
+	defer func(d time.Duration) { ttlWaitTimeout = d }(ttlWaitTimeout)
+	ttlWaitTimeout = 10 * time.Millisecond
+	db := dynagotest.New()
+	db.StatusDelay = 1000
+	if err := CreateTable(db, Lease{}, 1, 1); err != context.DeadlineExceeded {
+		t.Errorf("expected the wait to time out, found %v", err)
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2016 Appittome. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dynaGo
+
+import (
+	"reflect"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// Put writes i to dynamoDB using Marshal.  If i is versioned and the
+// stored item has moved on, a *VersionConflictError is returned.  When
+// i is a pointer, its version and timestamp fields are updated to the
+// values that were written.
+//
+// Put replaces the whole item: a zero createdAt field is stamped with the
+// time of the Put even if the item exists.  Only Update keeps the
+// createdAt of a stored item.
+//
+// If i has "unique" fields, the write is made in a transaction that also
+// maintains their sentinels, and a value already taken is reported as a
+// *UniqueConstraintError.
+func Put(svc dynamodbiface.DynamoDBAPI, i interface{}) error {
+	return PutWithContext(aws.BackgroundContext(), svc, i)
+}
+
+// PutWithContext is Put with a context and request options for the
+// requests made.
+func PutWithContext(ctx aws.Context, svc dynamodbiface.DynamoDBAPI, i interface{}, opts ...request.Option) error {
+	if len(getUniqueAttrNames(reflectType(i))) > 0 {
+		return txFailure(uniqueWrite(ctx, svc, i, false, opts))
+	}
+	in := Marshal(i)
+	if _, err := svc.PutItemWithContext(ctx, in, requestOptions(ctx, opts)...); err != nil {
+		return writeError(i, err)
+	}
+	syncItem(i, in.Item)
+	return nil
+}
+
+// Update writes i to dynamoDB using MarshalUpdate.  Errors and version
+// handling are the same as for Put, the fields of a pointer are updated
+// from the attributes returned by dynamoDB.
+func Update(svc dynamodbiface.DynamoDBAPI, i interface{}) error {
+	return UpdateWithContext(aws.BackgroundContext(), svc, i)
+}
+
+// UpdateWithContext is Update with a context and request options for
+// the requests made.
+func UpdateWithContext(ctx aws.Context, svc dynamodbiface.DynamoDBAPI, i interface{}, opts ...request.Option) error {
+	if len(getUniqueAttrNames(reflectType(i))) > 0 {
+		return txFailure(uniqueWrite(ctx, svc, i, true, opts))
+	}
+	in := MarshalUpdate(i)
+	in.ReturnValues = aws.String(dynamodb.ReturnValueUpdatedNew)
+	out, err := svc.UpdateItemWithContext(ctx, in, requestOptions(ctx, opts)...)
+	if err != nil {
+		return writeError(i, err)
+	}
+	syncItem(i, out.Attributes)
+	return nil
+}
+
+// Delete removes the item with key kv.  The sentinels of its "unique"
+// fields are removed in the same transaction.
+func Delete(svc dynamodbiface.DynamoDBAPI, km KeyMaker, kv ...interface{}) error {
+	return DeleteWithContext(aws.BackgroundContext(), svc, km, kv)
+}
+
+// DeleteWithContext is Delete with a context and request options for the
+// requests made.
+func DeleteWithContext(ctx aws.Context, svc dynamodbiface.DynamoDBAPI, km KeyMaker, kv []interface{}, opts ...request.Option) error {
+	k, err := km(kv...)
+	if err != nil {
+		return err
+	}
+	return deleteKey(ctx, svc, k, opts)
+}
+
+// DeleteItem removes the item with the key of i, see KeyFrom.
+func DeleteItem(svc dynamodbiface.DynamoDBAPI, i interface{}) error {
+	return DeleteItemWithContext(aws.BackgroundContext(), svc, i)
+}
+
+// DeleteItemWithContext is DeleteItem with a context and request options
+// for the requests made.
+func DeleteItemWithContext(ctx aws.Context, svc dynamodbiface.DynamoDBAPI, i interface{}, opts ...request.Option) error {
+	k, err := KeyFrom(i)
+	if err != nil {
+		return err
+	}
+	return deleteKey(ctx, svc, k, opts)
+}
+
+func deleteKey(ctx aws.Context, svc dynamodbiface.DynamoDBAPI, k key, opts []request.Option) error {
+	if len(getUniqueAttrNames(k.typ)) > 0 {
+		return txFailure(uniqueDelete(ctx, svc, k, opts))
+	}
+	_, err := svc.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{TableName: &k.tbln, Key: k.attr}, requestOptions(ctx, opts)...)
+	return err
+}
+
+// a transaction made on behalf of a single item reports the specific
+// error of the failure, if there is one
+func txFailure(err error) error {
+	if tce, ok := err.(*TxCanceledError); ok {
+		for _, f := range tce.Failures {
+			if f.Err != nil {
+				return f.Err
+			}
+		}
+	}
+	return err
+}
+
+// translates a failed condition on a versioned item to a
+// VersionConflictError, any other error is returned as is.
+func writeError(i interface{}, err error) error {
+	if !isErrCode(err, dynamodb.ErrCodeConditionalCheckFailedException) {
+		return err
+	}
+	v := reflect.Indirect(reflect.ValueOf(i))
+	vi := getVersionField(v.Type())
+	if vi == nil {
+		return err
+	}
+	return &VersionConflictError{
+		TableName: TableName(v.Type()),
+		Version:   v.FieldByIndex(vi).Int(),
+		Err:       err,
+	}
+}
+
+// after a successful write the fields dynaGo maintains on a *struct
+// (version, createdAt, updatedAt) are set from the written item, so
+// the struct matches what is stored and can be written again.
+func syncItem(i interface{}, item map[string]*dynamodb.AttributeValue) {
+	rv := reflect.ValueOf(i)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return
+	}
+	v := rv.Elem()
+	for _, o := range []string{tagVersion, tagCreatedAt, tagUpdatedAt} {
+		sf, ok := getOptionField(v.Type(), o)
+		if !ok {
+			continue
+		}
+		if av, ok := item[getAttrName(sf)]; ok {
+			f := v.FieldByIndex(sf.Index)
+			decoder(f.Type())(av, f)
+		}
+	}
+}